@@ -0,0 +1,32 @@
+package cle
+
+// suggestion returns the rest of the most recent command in the history that
+// begins with the input, to be suggested after the cursor (see AutoSuggest).
+// Suggestions are only made with the cursor at the end of the input.
+func (this *CLE) suggestion() []rune {
+	if !this.autoSuggest || this.password || this.search.active || len(this.data) == 0 || this.cursorPosition != len(this.data) {
+		return nil
+	}
+	input := string(this.data)
+	for i := len(this.history.commands) - 1; i >= 0; i-- {
+		command := string(this.history.commands[i])
+		if len(command) > len(input) && command[:len(input)] == input {
+			return []rune(command[len(input):])
+		}
+	}
+	return nil
+}
+
+// handledSuggestion accepts the suggestion, if there is one: all of it, or
+// else up to the end of its first word.
+func (this *CLE) handledSuggestion(all bool) bool {
+	suggestion := this.suggestion()
+	if len(suggestion) == 0 {
+		return false
+	}
+	if !all {
+		suggestion = suggestion[:wordRight(suggestion, 0)]
+	}
+	this.insertRunes(suggestion)
+	return true
+}
@@ -0,0 +1,89 @@
+package cle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestAutoSuggestFixture(t *testing.T) {
+	gunit.Run(new(AutoSuggestFixture), t)
+}
+
+type AutoSuggestFixture struct {
+	*gunit.Fixture
+	cle *CLE
+}
+
+func (this *AutoSuggestFixture) Setup() {
+	this.cle = NewCLE(TestMode(true), AutoSuggest(true))
+	this.cle.history.commands = [][]byte{[]byte("git status"), []byte("git commit -m wip"), []byte("ls")}
+}
+
+func (this *AutoSuggestFixture) TestSuggestsTheMostRecentMatchingCommand() {
+	this.So(press(this.cle, 'g', 'i'), should.BeNil)
+	this.So(string(this.cle.suggestion()), should.Equal, "t commit -m wip")
+
+	this.So(press(this.cle, 't', ' ', 's'), should.BeNil)
+	this.So(string(this.cle.suggestion()), should.Equal, "tatus")
+
+	this.So(press(this.cle, 'x'), should.BeNil)
+	this.So(this.cle.suggestion(), should.BeEmpty)
+}
+
+func (this *AutoSuggestFixture) TestNoSuggestion() {
+	this.So(this.cle.suggestion(), should.BeEmpty) // empty input
+
+	this.So(press(this.cle, 'l', 's'), should.BeNil)
+	this.So(this.cle.suggestion(), should.BeEmpty) // the whole command
+
+	this.cle.data = []rune("gi")
+	this.cle.cursorPosition = 1
+	this.So(this.cle.suggestion(), should.BeEmpty) // cursor not at the end
+
+	this.cle.autoSuggest = false
+	this.cle.cursorPosition = 2
+	this.So(this.cle.suggestion(), should.BeEmpty)
+}
+
+func (this *AutoSuggestFixture) TestRightArrowAcceptsTheSuggestion() {
+	this.So(press(this.cle, 'g', 'i', ESCAPE_KEY, ARROW_KEY_INDICATOR, RIGHT_ARROW), should.BeNil)
+	this.So(string(this.cle.data), should.Equal, "git commit -m wip")
+	this.So(this.cle.cursorPosition, should.Equal, len(this.cle.data))
+}
+
+func (this *AutoSuggestFixture) TestEndAcceptsTheSuggestion() {
+	this.So(press(this.cle, 'g', 'i', 't', ' ', 's', ESCAPE_KEY, ARROW_KEY_INDICATOR, 'F'), should.BeNil)
+	this.So(string(this.cle.data), should.Equal, "git status")
+}
+
+func (this *AutoSuggestFixture) TestAltFAcceptsOneWord() {
+	this.So(press(this.cle, 'g', 'i', ESCAPE_KEY, 'f'), should.BeNil)
+	this.So(string(this.cle.data), should.Equal, "git")
+	this.So(press(this.cle, ESCAPE_KEY, 'f'), should.BeNil)
+	this.So(string(this.cle.data), should.Equal, "git commit")
+}
+
+func (this *AutoSuggestFixture) TestRightArrowWithinTheInputMovesTheCursor() {
+	this.So(press(this.cle, 'g', 'i', ESCAPE_KEY, ARROW_KEY_INDICATOR, LEFT_ARROW, ESCAPE_KEY, ARROW_KEY_INDICATOR, RIGHT_ARROW), should.BeNil)
+	this.So(string(this.cle.data), should.Equal, "gi")
+	this.So(this.cle.cursorPosition, should.Equal, 2)
+}
+
+func (this *AutoSuggestFixture) TestSuggestionIsRenderedDimAfterTheCursor() {
+	output := new(bytes.Buffer)
+	this.cle.output = output
+	this.cle.testMode = false
+	this.cle.prompt = "> "
+	this.cle.data = []rune("l")
+	this.cle.cursorPosition = 1
+
+	this.cle.repaint()
+	this.So(output.String(), should.Equal, "\r\x1b[J> l"+SGR_RESET+SGR_DIM+"s"+SGR_RESET+"\r\x1b[3C")
+
+	output.Reset()
+	this.cle.crlf()
+	this.So(output.String(), should.Equal, "\x1b[J\n\r")
+}
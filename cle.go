@@ -2,29 +2,42 @@ package cle
 
 import (
 	"bufio"
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
-	"unicode/utf8"
-
-	"github.com/pkg/term"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 const (
-	TTY = "/dev/tty" // Microsoft Windows is not supported
+	TTY = "/dev/tty" // default input device; Microsoft Windows is not supported
 
 	HISTORY_MAX_DEFAULT           = 100
 	HISTORY_ENTRY_LEN_MIN_DEFAULT = 5
+	HISTORY_CONTROL_DEFAULT       = IgnoreDups
 	REPORT_ERRORS_DEFAULT         = false
 	SEARCH_MODE_CHAR_DEFAULT      = ':'
+	TERMINAL_WIDTH_DEFAULT        = 80
+	CONTINUATION_PROMPT_DEFAULT   = "> "
+	KILL_RING_SIZE                = 10
+	BRACKETED_PASTE_DEFAULT       = true
+	ESCAPE_TIMEOUT_DEFAULT        = 100 * time.Millisecond
 
 	CONTROL_A           = 1
 	CONTROL_B           = 2
 	CONTROL_D           = 4
 	CONTROL_E           = 5
+	CONTROL_G           = 7
+	TAB_KEY             = 9
 	CONTROL_K           = 11
 	CONTROL_N           = 14
+	CONTROL_R           = 18
+	CONTROL_S           = 19
 	CONTROL_W           = 23
 	ENTER_KEY           = 13
 	ESCAPE_KEY          = 27
@@ -33,28 +46,101 @@ const (
 	RIGHT_ARROW         = 67
 	LEFT_ARROW          = 68
 	ARROW_KEY_INDICATOR = 91
+	SS3_INDICATOR       = 79
 	DELETE_KEY          = 127
+
+	BRACKETED_PASTE_ON  = "\x1b[?2004h"
+	BRACKETED_PASTE_OFF = "\x1b[?2004l"
+	PASTE_START         = "\x1b[200~"
+	PASTE_END           = "\x1b[201~"
+
+	SGR_RESET   = "\x1b[0m"
+	SGR_REVERSE = "\x1b[7m"
+	SGR_DIM     = "\x1b[2m"
+)
+
+var (
+	// ErrInterrupted is returned by ReadLine when CTL-C is pressed or the read is interrupted before a line is entered.
+	ErrInterrupted = errors.New("cle: interrupted")
+
+	// ErrEOF is returned by ReadLine when the input is exhausted or CTL-D is pressed on an empty line.
+	ErrEOF = errors.New("cle: end of input")
+
+	// ErrNoTerminal is returned by ReadLine when the terminal cannot be opened or put into raw mode.
+	ErrNoTerminal = errors.New("cle: no terminal available")
+
+	// ErrClosed is returned by ReadLine once the CLE is closed.
+	ErrClosed = errors.New("cle: closed")
 )
 
 type CLE struct {
 	data           []rune
 	searchFor      []rune
-	terminal       *term.Term
 	prompt         string
 	cursorPosition int
 	history        CommandHistory
+	completion     completion
+	search         incrementalSearch
+	rendered       rendering
+	vi             vi
+	edits          editHistory
+	kills          killRing
+
+	input    io.Reader // configured input; the TTY is opened for each read when nil
+	output   io.Writer
+	terminal Terminal // configured terminal; the TTY when nil and no input is configured
+	tty      *ttyTerminal
+	pump     *inputPump  // reads the configured input in the background
+	reader   inputSource // the input of the current read
+	control  Terminal    // the terminal of the current read, if any
+	decoder  keyDecoder  // holds input not yet handled, e.g. keys typed ahead
+	resized  int32       // set (atomically) when the terminal is resized during a read
+	aborted  int32       // set (atomically) when the terminal is restored during a read
+
+	terminalLock sync.Mutex // guards the terminal mode, which Restore may change from any goroutine
+	rawMode      bool
+	closed       int32 // set (atomically) by Close
+	failure      error // an error not returned otherwise, for the next read to return (see fail)
+
+	displayLock sync.Mutex // guards the line and its display during a read, except while waiting for input
+	displaying  bool
 
 	historyFile               string
 	historyMax                int
 	historyEntryMinimumLength int
+	historyAppend             bool
+	historyFormat             HistoryFileFormat
+	historyStore              HistoryStore
+	historyControl            HistoryPolicy
+	historyIgnores            []historyPattern
+	redactors                 []Redactor
+	historyKey                []byte
+	migrateHistory            bool
+	ignorePatterns            []string
+	session                   string
 	searchModeChar            byte
+	escapeTimeout             time.Duration
+	keyMap                    KeyMap
+	bindings                  []Binding
+	completer                 Completer
+	validator                 Validator
+	highlighter               Highlighter
+	autoSuggest               bool
+	mask                      rune
+	password                  bool // the read is of ReadPassword
+	continuationPrompt        string
+	viMode                    bool
+	bracketedPaste            bool
+	handledSignals            bool
 	reportErrors              bool
 	testMode                  bool
 }
 
 type CommandHistory struct {
 	commands        [][]byte
+	details         []historyDetails // of each command, as far as known
 	currentPosition int
+	saved           int // the number of commands, from the first, that are in the history file
 }
 
 func NewCLE(options ...Option) *CLE {
@@ -64,312 +150,451 @@ func NewCLE(options ...Option) *CLE {
 func (this *CLE) configure(options []Option) *CLE {
 	this.historyMax = HISTORY_MAX_DEFAULT
 	this.historyEntryMinimumLength = HISTORY_ENTRY_LEN_MIN_DEFAULT
+	this.historyControl = HISTORY_CONTROL_DEFAULT
 	this.reportErrors = REPORT_ERRORS_DEFAULT
 	this.history = CommandHistory{}
+	this.session = newSessionID()
 	this.searchModeChar = SEARCH_MODE_CHAR_DEFAULT
+	this.output = os.Stdout
+	this.continuationPrompt = CONTINUATION_PROMPT_DEFAULT
+	this.escapeTimeout = ESCAPE_TIMEOUT_DEFAULT
+	this.bracketedPaste = BRACKETED_PASTE_DEFAULT
+	this.keyMap = LegacyKeyMap()
 
 	for _, configure := range options {
 		configure(this)
 	}
 
-	this.loadHistory(nil)
+	for _, binding := range this.bindings {
+		if binding.Action == "" {
+			delete(this.keyMap, binding.Key)
+		} else {
+			this.keyMap[binding.Key] = binding.Action
+		}
+	}
+
+	this.historyIgnores = this.compileIgnorePatterns(this.ignorePatterns)
+	if this.historyStore == nil && len(this.historyFile) > 0 {
+		this.historyStore = this.newHistoryFileStore()
+	}
+	this.fail(this.loadHistory(nil))
 	return this
 }
 
+// ReadInput displays the prompt and returns the line entered by the user.
+// Any error (see ReadLine) results in a nil return value.
 func (this *CLE) ReadInput(prompt string) []byte {
+	line, err := this.readLine(context.Background(), prompt)
+	if this.handleError(err) {
+		return nil
+	}
+	return line
+}
+
+// ReadLine displays the prompt and returns the line entered by the user.
+// The error is ErrEOF when the input is exhausted or CTL-D is pressed on an
+// empty line, ErrInterrupted when CTL-C is pressed or the read is
+// interrupted (e.g. by Restore), ErrNoTerminal (wrapping the cause) when the
+// terminal is unavailable, ErrClosed after Close, or any other error
+// reported while reading from the terminal. Before reading, it returns any
+// error that no other method returned, once: of an option (see
+// IgnorePatterns), or of the history store, e.g. ErrHistoryDecryption when
+// NewCLE loads the history (see LoadHistory and SaveHistoryErr).
+func (this *CLE) ReadLine(prompt string) (string, error) {
+	line, err := this.readLine(context.Background(), prompt)
+	return string(line), err
+}
+
+func (this *CLE) readLine(ctx context.Context, prompt string) ([]byte, error) {
+	this.displayLock.Lock()
+	defer this.displayLock.Unlock()
+
 	this.prompt = prompt
 	this.data = []rune{}
 	this.cursorPosition = 0
-	this.repaint()
+	this.rendered = rendering{}
+	this.edits = editHistory{}
+	this.resetVi()
 
-	this.openTty()
-	if this.terminal == nil {
-		return nil
+	if atomic.LoadInt32(&this.closed) != 0 {
+		return nil, ErrClosed
+	}
+	if err := this.failure; err != nil {
+		this.failure = nil
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	atomic.StoreInt32(&this.aborted, 0)
+	if err := this.openTty(); err != nil {
+		return nil, err
 	}
 	defer this.closeTty()
+	defer this.guardTerminal()()
+	defer this.watchResize()()
+	defer this.watchContext(ctx)()
+	defer this.restoreOnPanic()
+	this.displaying = true
+	defer func() { this.displaying = false }()
+	this.repaint()
 
-	var carry []byte // holds an incomplete trailing UTF-8 sequence split across reads
 	for {
-		buffer := make([]byte, 6)
-		numRead, err := this.terminal.Read(buffer)
-		if this.handleError(err) {
-			continue
+		key, err := this.readKey()
+		if err == ErrInterrupted && ctx.Err() != nil {
+			return nil, this.cancel(ctx)
 		}
-
-		// Prepend any bytes carried over from a multibyte character that was
-		// split across the previous read so it can be decoded as a whole.
-		work := buffer[:numRead]
-		if len(carry) > 0 {
-			work = append(carry, work...)
-			carry = nil
+		if err != nil {
+			return nil, err
 		}
-		numRead = len(work)
 
-		if this.handleArrowKeys(numRead, work) {
-			continue
+		switch err := this.handleKey(key); err {
+		case nil:
+		case errLineAccepted:
+			return runeBytes(this.data), nil
+		default:
+			return nil, err
 		}
+	}
+}
 
-		if this.handleDeleteKey(numRead, work) {
-			continue
+// readKey returns the next key typed, reading more input as needed. Input
+// that stops partway through an escape sequence for longer than the escape
+// timeout is taken as it stands, e.g. a lone ESC is the Escape key.
+func (this *CLE) readKey() (Key, error) {
+	for {
+		if atomic.CompareAndSwapInt32(&this.aborted, 1, 0) {
+			return Key{}, ErrInterrupted
 		}
-
-		if this.handleControlKeys(numRead, work) {
-			continue
+		if key, decoded := this.decoder.decode(false); decoded {
+			return key, nil
 		}
 
-		if this.handleEnterKey(numRead, work) {
-			return []byte(string(this.data))
+		timeout := time.Duration(-1)
+		if this.decoder.pending() {
+			timeout = this.escapeTimeout
 		}
-
-		if this.handleAnySingleKey(numRead, work) {
-			continue
+		input, err := this.awaitInput(timeout)
+		if err != nil && atomic.CompareAndSwapInt32(&this.aborted, 1, 0) {
+			return Key{}, ErrInterrupted // e.g. the input stopped by Close
 		}
-
-		carry = this.handlePaste(work)
+		if err != nil {
+			return Key{}, readError(err)
+		}
+		if input == nil && !this.handledResize() {
+			if key, decoded := this.decoder.decode(true); decoded {
+				return key, nil
+			}
+		}
+		this.decoder.write(input)
 	}
 }
 
-func (this *CLE) handleArrowKeys(numRead int, work []byte) bool {
-	// Special case: Option+D on macOS Terminal.app sends ∂ (U+2202, UTF-8: 0xE2 0x88 0x82)
-	if numRead == 3 && work[0] == 0xE2 && work[1] == 0x88 && work[2] == 0x82 {
-		this.handledWordDeleteRight()
-		this.repaint()
-		return true
+// acceptLine completes the line, unless the validator reports the input as
+// incomplete, in which case it continues on a new line.
+func (this *CLE) acceptLine() error {
+	if this.password {
+		this.crlf()
+		return errLineAccepted
 	}
 
-	if numRead < 2 || work[0] != ESCAPE_KEY {
-		return false
+	if this.validator != nil && !this.validator.Validate(this.data) {
+		this.insertRunes([]rune{'\n'})
+		return nil
 	}
 
-	// ESC b: Alt+Left (readline-style, e.g. macOS Terminal.app)
-	if numRead == 2 && work[1] == 'b' {
-		this.handledAltLeftArrow()
-		this.repaint()
-		return true
+	if len(this.data) > 0 && this.data[0] == rune(this.searchModeChar) {
+		this.clearInputData()
+		return errLineAccepted
 	}
 
-	// ESC f: Alt+Right (readline-style, e.g. macOS Terminal.app)
-	if numRead == 2 && work[1] == 'f' {
-		this.handledAltRightArrow()
-		this.repaint()
-		return true
-	}
+	this.clearSearchMode()
+	this.crlf()
 
-	// ESC DEL: Alt+Backspace (delete word left)
-	if numRead == 2 && work[1] == DELETE_KEY {
-		this.handledWordDeleteLeft()
-		this.repaint()
-		return true
+	if string(this.data) == "!clear" {
+		this.ClearHistory()
+		this.clearInputData()
+		return errLineAccepted
 	}
 
-	// ESC d: Alt+D (delete word forward)
-	if numRead == 2 && work[1] == 'd' {
-		this.handledWordDeleteRight()
-		this.repaint()
-		return true
-	}
+	this.saveHistoryEntry()
+	return errLineAccepted
+}
 
-	// ESC [ 1 ; 3 D: Alt+Left (xterm-style)
-	if numRead == 6 && work[1] == ARROW_KEY_INDICATOR && work[2] == '1' && work[3] == ';' && work[4] == '3' && work[5] == LEFT_ARROW {
-		this.handledAltLeftArrow()
-		this.repaint()
-		return true
+func (this *CLE) beginningOfLine() error {
+	this.cursorPosition = 0
+	this.repaint()
+	return nil
+}
+
+func (this *CLE) endOfLine() error {
+	if this.handledSuggestion(true) {
+		return nil
 	}
+	this.cursorPosition = len(this.data)
+	this.repaint()
+	return nil
+}
 
-	// ESC [ 1 ; 3 C: Alt+Right (xterm-style)
-	if numRead == 6 && work[1] == ARROW_KEY_INDICATOR && work[2] == '1' && work[3] == ';' && work[4] == '3' && work[5] == RIGHT_ARROW {
-		this.handledAltRightArrow()
+func (this *CLE) backwardChar() error {
+	if this.handledLeftArrow() {
 		this.repaint()
-		return true
 	}
+	return nil
+}
 
-	if numRead != 3 || work[1] != ARROW_KEY_INDICATOR {
-		return false
+func (this *CLE) forwardChar() error {
+	if this.handledSuggestion(true) {
+		return nil
 	}
-
-	switch work[2] {
-	case UP_ARROW:
-		if !this.handledUpArrow() {
-			return true
-		}
-		this.populateDataWithHistoryEntry()
+	if this.handledRightArrow() {
 		this.repaint()
+	}
+	return nil
+}
 
-	case DOWN_ARROW:
-		if !this.handledDownArrow() {
-			if this.isSearching() {
-				this.data = append(this.data[:0], rune(this.searchModeChar))
-				this.data = append(this.data, this.searchFor...)
-				this.cursorPosition = len(this.data)
-				this.clearSearchMode()
-				this.repaint()
-				return true
-			}
-			this.clearInputData()
-			this.repaint()
-			return true
-		}
-		this.populateDataWithHistoryEntry()
-		this.repaint()
+func (this *CLE) backwardWord() error {
+	this.handledAltLeftArrow()
+	this.repaint()
+	return nil
+}
 
-	case RIGHT_ARROW:
-		if !this.handledRightArrow() {
-			return true
-		}
-		this.repaint()
+func (this *CLE) forwardWord() error {
+	if this.handledSuggestion(false) {
+		return nil
+	}
+	this.handledAltRightArrow()
+	this.repaint()
+	return nil
+}
 
-	case LEFT_ARROW:
-		if !this.handledLeftArrow() {
-			return true
-		}
+func (this *CLE) previousHistory() error {
+	if this.password {
+		return nil
+	}
+	if this.handledLineUp() {
 		this.repaint()
+		return nil
 	}
-
-	return true
+	if !this.handledUpArrow() {
+		return nil
+	}
+	this.populateDataWithHistoryEntry()
+	this.repaint()
+	return nil
 }
 
-func (this *CLE) handleEnterKey(numRead int, work []byte) bool {
-	if numRead != 1 || work[0] != ENTER_KEY {
-		return false
+func (this *CLE) nextHistory() error {
+	if this.password {
+		return nil
 	}
-
-	if len(this.data) > 0 && this.data[0] == rune(this.searchModeChar) {
-		this.clearInputData()
-		return true
+	if this.handledLineDown() {
+		this.repaint()
+		return nil
 	}
-
-	this.clearSearchMode()
-	this.crlf()
-
-	if string(this.data) == "!clear" {
-		this.ClearHistory()
+	if this.handledDownArrow() {
+		this.populateDataWithHistoryEntry()
+	} else if this.isSearching() {
+		this.data = append(this.data[:0], rune(this.searchModeChar))
+		this.data = append(this.data, this.searchFor...)
+		this.cursorPosition = len(this.data)
+		this.clearSearchMode()
+	} else {
 		this.clearInputData()
-		return true
 	}
-
-	this.saveHistoryEntry()
-	return true
+	this.repaint()
+	return nil
 }
 
-func (this *CLE) handleDeleteKey(numRead int, work []byte) bool {
-	if numRead != 1 || work[0] != DELETE_KEY {
-		return false
-	}
-
+func (this *CLE) backwardDeleteChar() error {
 	if this.cursorPosition == 0 {
-		return true
+		return nil
 	}
 
-	this.cursorPosition--
-	this.data = remove(this.data, this.cursorPosition)
+	start := graphemeLeft(this.data, this.cursorPosition)
+	this.data = append(this.data[:start], this.data[this.cursorPosition:]...)
+	this.cursorPosition = start
 	this.repaint()
-	return true
+	return nil
 }
 
-func (this *CLE) handleControlKeys(numRead int, work []byte) bool {
-	if numRead != 1 || !isControlKey(numRead, work) {
-		return false
+// deleteChar deletes the current character, or ends the input when the line is empty.
+func (this *CLE) deleteChar() error {
+	if len(this.data) == 0 {
+		this.crlf()
+		return ErrEOF
 	}
+	return this.deleteForwardChar()
+}
 
-	switch work[0] {
-	case CONTROL_A: // beginning of line
-		this.cursorPosition = 0
-		this.repaint()
-	case CONTROL_B: // delete to beginning of line
-		this.data = this.data[this.cursorPosition:]
-		this.cursorPosition = 0
-		this.repaint()
-	case CONTROL_D: // delete current character
-		if this.cursorPosition < len(this.data) {
-			this.data = remove(this.data, this.cursorPosition)
-			this.repaint()
-		}
-	case CONTROL_E: // end of line
-		this.cursorPosition = len(this.data)
-		this.repaint()
-	case CONTROL_K: // delete current character to end of line
-		this.data = this.data[:this.cursorPosition]
-		this.repaint()
-	case CONTROL_N: // delete entire line
-		this.data = this.data[:0]
-		this.cursorPosition = 0
-		this.repaint()
-	case CONTROL_W: // delete word to the left; if char immediately left is whitespace, delete it too then the word left
-		this.handledWordDeleteLeft()
+// deleteForwardChar deletes the current character.
+func (this *CLE) deleteForwardChar() error {
+	if this.cursorPosition < len(this.data) {
+		end := graphemeRight(this.data, this.cursorPosition)
+		this.data = append(this.data[:this.cursorPosition], this.data[end:]...)
 		this.repaint()
 	}
-	return true
+	return nil
 }
 
-func (this *CLE) handleAnySingleKey(numRead int, work []byte) bool {
-	if numRead != 1 {
-		return false
-	}
+// killLine deletes the current character to the end of the line.
+func (this *CLE) killLine() error {
+	this.kill(this.cursorPosition, len(this.data))
+	this.repaint()
+	return nil
+}
 
-	if !isPrintable(work[0]) {
-		return true
+// unixLineDiscard deletes to the beginning of the line.
+func (this *CLE) unixLineDiscard() error {
+	this.kill(0, this.cursorPosition)
+	this.repaint()
+	return nil
+}
+
+// killWholeLine deletes the entire line.
+func (this *CLE) killWholeLine() error {
+	this.kill(0, len(this.data))
+	this.repaint()
+	return nil
+}
+
+// unixWordRubout deletes the word to the left; if the character immediately
+// left is whitespace, it is deleted too, and then the word to its left.
+func (this *CLE) unixWordRubout() error {
+	this.handledWordDeleteLeft()
+	this.repaint()
+	return nil
+}
+
+func (this *CLE) killWord() error {
+	this.handledWordDeleteRight()
+	this.repaint()
+	return nil
+}
+
+// transposeChars swaps the characters before and at the cursor, or the two
+// characters before the cursor at the end of the line.
+func (this *CLE) transposeChars() error {
+	if len(this.data) < 2 || this.cursorPosition == 0 {
+		return nil
 	}
-	this.data = insert(this.data, this.cursorPosition, rune(work[0]))
+	if this.cursorPosition == len(this.data) {
+		this.cursorPosition--
+	}
+	this.data[this.cursorPosition-1], this.data[this.cursorPosition] = this.data[this.cursorPosition], this.data[this.cursorPosition-1]
 	this.cursorPosition++
 	this.repaint()
-	return true
+	return nil
 }
 
-// handlePaste decodes work as UTF-8 and inserts each printable character at the
-// cursor. If work ends with an incomplete multibyte sequence (a character split
-// across terminal reads), those trailing bytes are returned so the caller can
-// prepend them to the next read.
-func (this *CLE) handlePaste(work []byte) (carry []byte) {
-	for len(work) > 0 {
-		if !utf8.FullRune(work) {
-			carry = work
-			break
-		}
-		r, size := utf8.DecodeRune(work)
-		work = work[size:]
-		if r == utf8.RuneError || !isInsertableRune(r) {
-			continue
-		}
+func (this *CLE) clearScreen() error {
+	this.write("\x1b[H\x1b[2J") // VT100 cursor home, clear screen
+	this.rendered = rendering{}
+	this.repaint()
+	return nil
+}
+
+// insertRunes inserts runes at the cursor, or appends them to the query of
+// the incremental search, if there is one.
+func (this *CLE) insertRunes(runes []rune) {
+	if this.search.active {
+		this.extendIncrementalSearch(runes)
+		this.repaint()
+		return
+	}
+	for _, r := range runes {
 		this.data = insert(this.data, this.cursorPosition, r)
 		this.cursorPosition++
 	}
 	this.repaint()
-	return carry
 }
 
-func (this *CLE) repaint() {
+// paste inserts text pasted in bracketed paste mode as a whole. Line breaks
+// are kept in multi-line input (see Validation) and are otherwise spaces, as
+// are tabs; other control characters are dropped.
+func (this *CLE) paste(text string) {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	runes := make([]rune, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '\n' && this.validator != nil && !this.search.active:
+			runes = append(runes, r)
+		case r == '\n' || r == '\t':
+			runes = append(runes, ' ')
+		case isInsertableRune(r):
+			runes = append(runes, r)
+		}
+	}
+	this.insertRunes(runes)
+}
+
+// crlf moves the terminal cursor below the rendered input to start a new line.
+func (this *CLE) crlf() {
 	if this.testMode {
 		return
 	}
 
-	fmt.Printf("%c%c%c%c", 27, '[', '2', 'K')                      // VT100 clear line
-	fmt.Printf("%c%s%s%c", 13, this.prompt, string(this.data), 32) // go to beginning and print data
-	for i := len(this.data) + 1; i > this.cursorPosition; i-- {    // backspace to the current cursor position
-		fmt.Printf("%c", 8)
+	if len(this.suggestion()) > 0 {
+		fmt.Fprint(this.output, "\x1b[J") // VT100 clear to end of screen: the suggestion after the cursor
+	} else if down := this.rendered.rows - 1 - this.rendered.cursorRow; down > 0 {
+		fmt.Fprintf(this.output, "\x1b[%dB", down) // VT100 cursor down to the last row
 	}
+	fmt.Fprintf(this.output, "%c%c", 10, 13)
+	this.rendered = rendering{}
 }
 
-func (this *CLE) crlf() {
+func (this *CLE) write(text string) {
 	if this.testMode {
 		return
 	}
 
-	fmt.Printf("%c%c", 10, 13)
+	fmt.Fprint(this.output, text)
 }
 
-func (this *CLE) openTty() {
-	var err error
-	this.terminal, err = term.Open(TTY)
-	if err != nil {
-		this.handleError(err)
-		return
+// terminalWidth reports the width of the terminal in columns, assuming the
+// VT100 default when the size cannot be determined.
+func (this *CLE) terminalWidth() int {
+	if this.control == nil {
+		return TERMINAL_WIDTH_DEFAULT
+	}
+	columns, _, err := this.control.Size()
+	if err != nil || columns <= 0 {
+		return TERMINAL_WIDTH_DEFAULT
+	}
+	return columns
+}
+
+func (this *CLE) openTty() error {
+	this.control = this.terminal
+	if this.input != nil {
+		if !this.startedInputPump() {
+			return ErrClosed
+		}
+		this.reader = this.pump
+	} else {
+		tty, err := openTtyTerminal(TTY)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNoTerminal, err)
+		}
+		this.tty, this.reader = tty, tty
+		if this.control == nil {
+			this.control = tty
+		}
+	}
+
+	if err := this.enterRawMode(); err != nil {
+		this.closeTty()
+		return fmt.Errorf("%w: %v", ErrNoTerminal, err)
 	}
-	this.handleError(term.RawMode(this.terminal))
+	return nil
 }
 
 func (this *CLE) closeTty() {
-	this.handleError(this.terminal.Restore())
-	this.handleError(this.terminal.Close())
+	this.handleError(this.leaveRawMode())
+	if this.tty != nil {
+		this.handleError(this.tty.Close())
+	}
+	this.tty, this.reader, this.control = nil, nil, nil
 }
 
 func (this *CLE) clearInputData() {
@@ -406,7 +631,7 @@ func (this *CLE) handledLeftArrow() bool {
 	if this.cursorPosition <= 0 {
 		return false
 	}
-	this.cursorPosition--
+	this.cursorPosition = graphemeLeft(this.data, this.cursorPosition)
 	return true
 }
 
@@ -416,32 +641,18 @@ func (this *CLE) handledRightArrow() bool {
 	if this.cursorPosition > len(this.data)-1 {
 		return false
 	}
-	this.cursorPosition++
+	this.cursorPosition = graphemeRight(this.data, this.cursorPosition)
 	return true
 }
 
 func (this *CLE) handledAltLeftArrow() {
 	this.clearSearchMode()
-	pos := this.cursorPosition
-	for pos > 0 && this.data[pos-1] == ' ' {
-		pos--
-	}
-	for pos > 0 && this.data[pos-1] != ' ' {
-		pos--
-	}
-	this.cursorPosition = pos
+	this.cursorPosition = wordLeft(this.data, this.cursorPosition)
 }
 
 func (this *CLE) handledAltRightArrow() {
 	this.clearSearchMode()
-	pos := this.cursorPosition
-	for pos < len(this.data) && this.data[pos] == ' ' {
-		pos++
-	}
-	for pos < len(this.data) && this.data[pos] != ' ' {
-		pos++
-	}
-	this.cursorPosition = pos
+	this.cursorPosition = wordRight(this.data, this.cursorPosition)
 }
 
 func (this *CLE) handledWordDeleteLeft() {
@@ -455,8 +666,7 @@ func (this *CLE) handledWordDeleteLeft() {
 	for start > 0 && this.data[start-1] != ' ' {
 		start--
 	}
-	this.data = append(this.data[:start], this.data[end:]...)
-	this.cursorPosition = start
+	this.kill(start, end)
 }
 
 func (this *CLE) handledWordDeleteRight() {
@@ -467,7 +677,31 @@ func (this *CLE) handledWordDeleteRight() {
 	for end < len(this.data) && this.data[end] != ' ' {
 		end++
 	}
-	this.data = append(this.data[:this.cursorPosition], this.data[end:]...)
+	this.kill(this.cursorPosition, end)
+}
+
+// handledLineUp moves the cursor to the previous line of multi-line input,
+// keeping its column where possible.
+func (this *CLE) handledLineUp() bool {
+	start := lineStart(this.data, this.cursorPosition)
+	if start == 0 {
+		return false
+	}
+	previous := lineStart(this.data, start-1)
+	this.cursorPosition = previous + min(this.cursorPosition-start, start-1-previous)
+	return true
+}
+
+// handledLineDown moves the cursor to the next line of multi-line input,
+// keeping its column where possible.
+func (this *CLE) handledLineDown() bool {
+	end := lineEnd(this.data, this.cursorPosition)
+	if end == len(this.data) {
+		return false
+	}
+	column := this.cursorPosition - lineStart(this.data, this.cursorPosition)
+	this.cursorPosition = end + 1 + min(column, lineEnd(this.data, end+1)-end-1)
+	return true
 }
 
 func (this *CLE) handledUpArrow() bool {
@@ -523,19 +757,25 @@ func (this *CLE) populateDataWithHistoryEntry() {
 
 func (this *CLE) saveHistoryEntry() {
 	if len(this.data) > this.historyEntryMinimumLength {
-		if this.commandIsAlreadyPreviousEntryInHistory() {
+		entry := []byte(this.redact(string(this.data)))
+		if this.ignoredByHistory(string(this.data), entry) {
 			return
 		}
 
-		entry := []byte(string(this.data))
-		this.history.commands = append(this.history.commands, entry)
+		if this.historyControl&EraseDups != 0 {
+			this.history.erase(entry)
+		}
+		this.history.add(entry, this.newHistoryDetails())
 		this.history.currentPosition = len(this.history.commands)
+		if this.historyAppend {
+			this.fail(this.appendHistory())
+		}
 	}
 }
 
-func (this *CLE) commandIsAlreadyPreviousEntryInHistory() bool {
+func (this *CLE) commandIsAlreadyPreviousEntryInHistory(entry []byte) bool {
 	return len(this.history.commands) > 0 &&
-		string(this.history.commands[len(this.history.commands)-1]) == string(this.data)
+		string(this.history.commands[len(this.history.commands)-1]) == string(entry)
 }
 
 func (this *CLE) getCurrentHistoryEntry() []byte {
@@ -549,74 +789,75 @@ func (this *CLE) getCurrentHistoryEntry() []byte {
 	return this.history.commands[this.history.currentPosition]
 }
 
-func (this *CLE) SaveHistory() {
-	this.writeHistoryFile(this.prepareHistoryForWriting())
-}
-
-func (this *CLE) prepareHistoryForWriting() (history []byte) {
-	// save the last n commands
-	startIndex := len(this.history.commands) - this.historyMax
-	if startIndex < 0 {
-		startIndex = 0
-	}
-	this.history.commands = this.history.commands[startIndex:]
+// trimHistory keeps the last n commands (see HistorySize).
+func (this *CLE) trimHistory() {
+	this.history.trim(this.historyMax)
 	this.history.currentPosition = len(this.history.commands)
-	for _, historyLine := range this.history.commands {
-		history = append(history, historyLine...)
-		history = append(history, '\n')
-	}
-	return history
 }
 
-func (this *CLE) loadHistory(scanner *bufio.Scanner) {
-	if len(this.historyFile) > 0 {
-		this.readHistoryFile()
+func (this *CLE) loadHistory(scanner *bufio.Scanner) (err error) {
+	if this.historyStore != nil {
+		err = this.loadHistoryStore()
 	}
 
 	if scanner != nil {
 		for scanner.Scan() {
-			this.history.commands = append(this.history.commands, scanner.Bytes())
+			this.history.add(append([]byte(nil), scanner.Bytes()...), historyDetails{})
 		}
 	}
-
-	this.history.currentPosition = len(this.history.commands)
+	this.tidyHistory()
+	return err
 }
 
-func (this *CLE) writeHistoryFile(history []byte) {
-	this.handleError(os.WriteFile(this.historyFile, history, 0644))
-}
-
-func (this *CLE) readHistoryFile() {
-	file, err := os.ReadFile(this.historyFile)
-	if os.IsNotExist(err) {
-		return
-	}
-	this.handleError(err)
-	scanner := bufio.NewScanner(bytes.NewReader(file))
-	for scanner.Scan() {
-		this.history.commands = append(this.history.commands, scanner.Bytes())
+// tidyHistory erases the duplicates (see EraseDups) and keeps the last n
+// commands (see HistorySize) of the history just loaded, all of them saved.
+func (this *CLE) tidyHistory() {
+	if this.historyControl&EraseDups != 0 {
+		this.history.eraseDuplicates()
 	}
+	this.history.trim(this.historyMax)
+	this.history.currentPosition = len(this.history.commands)
+	this.history.saved = len(this.history.commands)
 }
 
 func (this *CLE) ClearHistory() {
 	this.history.commands = this.history.commands[:0]
+	this.history.details = this.history.details[:0]
 	this.history.currentPosition = 0
-	if len(this.historyFile) > 0 {
-		this.handleError(os.Remove(this.historyFile))
+	this.history.saved = 0
+	if this.historyStore != nil {
+		this.fail(this.historyStore.Clear())
+	}
+}
+
+// fail keeps an error that cannot be returned where it happens, e.g. in
+// NewCLE, for the next read to return (see ReadLine), so that it does not go
+// unnoticed; an earlier error still kept takes precedence.
+func (this *CLE) fail(err error) {
+	if err != nil && this.failure == nil {
+		this.failure = err
 	}
 }
 
 func (this *CLE) handleError(err error) bool {
 	if err != nil && this.reportErrors {
-		fmt.Println(err)
+		fmt.Fprintln(this.output, err)
 	}
 	return err != nil
 }
 
 ////////////////////////////////////////////
 
-func isPrintable(c byte) bool {
-	return c >= 32 && c <= 126
+// readError translates an error returned while reading the terminal into the
+// corresponding sentinel error, if there is one.
+func readError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return ErrEOF
+	}
+	if errors.Is(err, syscall.EINTR) {
+		return ErrInterrupted
+	}
+	return err
 }
 
 // isInsertableRune reports whether a decoded character should be inserted into
@@ -627,8 +868,51 @@ func isInsertableRune(r rune) bool {
 	return r >= 32 && r != 127
 }
 
-func isControlKey(numRead int, work []byte) bool {
-	return numRead == 1 && work[0] < ESCAPE_KEY && work[0] != ENTER_KEY
+// wordLeft returns the position of the beginning of the word left of position.
+func wordLeft(data []rune, position int) int {
+	for position > 0 && data[position-1] == ' ' {
+		position--
+	}
+	for position > 0 && data[position-1] != ' ' {
+		position--
+	}
+	return position
+}
+
+// wordRight returns the position following the end of the word right of position.
+func wordRight(data []rune, position int) int {
+	for position < len(data) && data[position] == ' ' {
+		position++
+	}
+	for position < len(data) && data[position] != ' ' {
+		position++
+	}
+	return position
+}
+
+// lineStart returns the position of the first character of the line of
+// multi-line input containing position.
+func lineStart(data []rune, position int) int {
+	for position > 0 && data[position-1] != '\n' {
+		position--
+	}
+	return position
+}
+
+// lineEnd returns the position of the newline ending the line of multi-line
+// input containing position, or the length of the input on the last line.
+func lineEnd(data []rune, position int) int {
+	for position < len(data) && data[position] != '\n' {
+		position++
+	}
+	return position
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }
 
 func insert(slice []rune, position int, value rune) []rune {
@@ -640,12 +924,3 @@ func insert(slice []rune, position int, value rune) []rune {
 	slice[position] = value
 	return slice
 }
-
-func remove(slice []rune, position int) []rune {
-	if position > len(slice)-1 {
-		return slice
-	}
-	ret := make([]rune, 0)
-	ret = append(ret, slice[:position]...)
-	return append(ret, slice[position+1:]...)
-}
@@ -3,8 +3,14 @@ package cle
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"io"
 	"os"
+	"strings"
+	"syscall"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/smarty/assertions/should"
 	"github.com/smarty/gunit"
@@ -33,25 +39,20 @@ func (this *CLEFixture) TestOptions() {
 	this.So(cleObj.reportErrors, should.BeTrue)
 }
 
-func (this *CLEFixture) TestHandleEnterKey() {
+func (this *CLEFixture) TestAcceptLine() {
 	cleObj := NewCLE(TestMode(true))
 
-	buffer := []byte{32, 0, 0}
-	handled := cleObj.handleEnterKey(1, buffer)
-	this.So(handled, should.BeFalse)
+	this.So(press(cleObj, 32), should.BeNil)
 
-	buffer = []byte{13, 0, 0}
 	cleObj.history.commands = cleObj.history.commands[:0]
 	cleObj.data = []rune("123456")
 	cleObj.cursorPosition = 2
-	handled = cleObj.handleEnterKey(1, buffer)
-	this.So(handled, should.BeTrue)
+	this.So(press(cleObj, 13), should.Equal, errLineAccepted)
 	this.So(cleObj.history.commands[0], should.Resemble, []byte("123456"))
 }
 
-func (this *CLEFixture) TestHandleEnterKeyClearHistory() {
+func (this *CLEFixture) TestAcceptLineClearHistory() {
 	cleObj := NewCLE(TestMode(true))
-	buffer := []byte{13, 0, 0}
 	cleObj.history.commands = cleObj.history.commands[:0]
 	cleObj.data = []rune("this is a history entry")
 	cleObj.saveHistoryEntry()
@@ -59,85 +60,66 @@ func (this *CLEFixture) TestHandleEnterKeyClearHistory() {
 	cleObj.saveHistoryEntry()
 	this.So(len(cleObj.history.commands), should.Equal, 2)
 	cleObj.data = []rune("!clear")
-	cleObj.handleEnterKey(1, buffer)
+	press(cleObj, ENTER_KEY)
 	this.So(len(cleObj.history.commands), should.Equal, 0)
 }
 
-func (this *CLEFixture) TestHandleDeleteKey() {
+func (this *CLEFixture) TestBackwardDeleteChar() {
 	cleObj := NewCLE(TestMode(true))
-	buffer := []byte{32, 0, 0}
-	handled := cleObj.handleDeleteKey(1, buffer)
-	this.So(handled, should.BeFalse)
 
-	buffer = []byte{127, 0, 0}
 	cleObj.data = []rune("123456")
 	cleObj.cursorPosition = 2
-	handled = cleObj.handleDeleteKey(1, buffer)
-	this.So(handled, should.BeTrue)
+	this.So(press(cleObj, 127), should.BeNil)
 	this.So(cleObj.data, should.Resemble, []rune("13456"))
 
-	buffer = []byte{127, 0, 0}
 	cleObj.data = []rune("123456")
 	cleObj.cursorPosition = 0
-	handled = cleObj.handleDeleteKey(1, buffer)
-	this.So(handled, should.BeTrue)
+	this.So(press(cleObj, 127), should.BeNil)
 	this.So(cleObj.data, should.Resemble, []rune("123456"))
 }
 
-func (this *CLEFixture) TestHandleAnySingleKey() {
+func (this *CLEFixture) TestInsertUnboundKeys() {
 	cleObj := NewCLE(TestMode(true))
 
-	buffer := []byte{'a', 'b', 0} // multiple keys are unhandled
-	handled := cleObj.handleAnySingleKey(2, buffer)
-	this.So(handled, should.BeFalse)
+	press(cleObj, 150) // not a character, not added to data
+	this.So(cleObj.data, should.Resemble, []rune(nil))
 
-	buffer = []byte{150, 0, 0} // unprintable is handled by not added to data
-	handled = cleObj.handleAnySingleKey(1, buffer)
-	this.So(handled, should.BeTrue)
+	press(cleObj, CONTROL_G) // unbound control key, not added to data
 	this.So(cleObj.data, should.Resemble, []rune(nil))
 
-	buffer = []byte{'a', 0, 0}
-	cleObj.handleAnySingleKey(1, buffer)
+	press(cleObj, 'a')
 	this.So(cleObj.data, should.Resemble, []rune("a"))
 
-	buffer = []byte{'b', 0, 0}
-	cleObj.handleAnySingleKey(1, buffer)
+	press(cleObj, 'b')
 	this.So(cleObj.data, should.Resemble, []rune("ab"))
 
 	cleObj.handledLeftArrow()
-	buffer = []byte{'c', 0, 0}
-	handled = cleObj.handleAnySingleKey(1, buffer)
+	press(cleObj, 'c')
 	this.So(cleObj.data, should.Resemble, []rune("acb"))
 }
 
-func (this *CLEFixture) TestHandlePaste() {
+func (this *CLEFixture) TestKeysInOneRead() {
 	cleObj := NewCLE(TestMode(true))
 
-	buffer := []byte{'a', 'b', 'c'}
-	cleObj.handlePaste(buffer)
-
-	buffer = []byte{'d', 0, 0}
-	cleObj.handlePaste(buffer)
+	press(cleObj, 'a', 'b', 'c')
+	press(cleObj, 'd', 0, 0)
 	this.So(cleObj.data, should.Resemble, []rune("abcd"))
 }
 
-func (this *CLEFixture) TestHandleArrowKeys() {
+func (this *CLEFixture) TestArrowKeys() {
 	cleObj := NewCLE(TestMode(true))
 
-	buffer := []byte{ESCAPE_KEY, 0, 0}
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeFalse)
-
-	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, UP_ARROW}
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	buffer := []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, UP_ARROW}
+	this.So(press(cleObj, buffer...), should.BeNil)
 
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, DOWN_ARROW}
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
 
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, RIGHT_ARROW}
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
 
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, LEFT_ARROW}
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
 
 	cleObj.data = []rune("this is a history entry")
 	cleObj.saveHistoryEntry()
@@ -146,64 +128,63 @@ func (this *CLEFixture) TestHandleArrowKeys() {
 
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, UP_ARROW}
 	cleObj.history.currentPosition = len(cleObj.history.commands)
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
 
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, DOWN_ARROW}
 	cleObj.history.currentPosition = 0
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
 
 	cleObj.data = []rune("abc")
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, RIGHT_ARROW}
 	cleObj.cursorPosition = 0
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
+	this.So(cleObj.cursorPosition, should.Equal, 1)
 
 	buffer = []byte{ESCAPE_KEY, ARROW_KEY_INDICATOR, LEFT_ARROW}
 	cleObj.cursorPosition = len(cleObj.data)
-	this.So(cleObj.handleArrowKeys(3, buffer), should.BeTrue)
+	this.So(press(cleObj, buffer...), should.BeNil)
+	this.So(cleObj.cursorPosition, should.Equal, 2)
 }
 
-func (this *CLEFixture) TestHandleControlKeys() {
+func (this *CLEFixture) TestControlKeys() {
 	cleObj := NewCLE(TestMode(true))
 
-	buffer := []byte{UP_ARROW, 0, 0}
-	this.So(cleObj.handleControlKeys(1, buffer), should.BeFalse)
-
 	cleObj.data = []rune("test command")
-	buffer = []byte{CONTROL_A, 0, 0}
+	buffer := []byte{CONTROL_A}
 	cleObj.cursorPosition = 5
-	cleObj.handleControlKeys(1, buffer)
+	press(cleObj, buffer...)
 	this.So(cleObj.cursorPosition, should.BeZeroValue)
 
 	cleObj.data = []rune("test command")
-	buffer = []byte{CONTROL_B, 0, 0}
+	buffer = []byte{CONTROL_B}
 	cleObj.cursorPosition = 5
-	cleObj.handleControlKeys(1, buffer)
+	press(cleObj, buffer...)
 	this.So(cleObj.cursorPosition, should.Equal, 0)
 	this.So(cleObj.data, should.Resemble, []rune("command"))
 
 	cleObj.data = []rune("test command")
-	buffer = []byte{CONTROL_D, 0, 0}
+	buffer = []byte{CONTROL_D}
 	cleObj.cursorPosition = 5
-	cleObj.handleControlKeys(1, buffer)
+	press(cleObj, buffer...)
 	this.So(cleObj.cursorPosition, should.Equal, 5)
 	this.So(cleObj.data, should.Resemble, []rune("test ommand"))
 
 	cleObj.data = []rune("test command")
-	buffer = []byte{CONTROL_E, 0, 0}
+	buffer = []byte{CONTROL_E}
 	cleObj.cursorPosition = 5
-	cleObj.handleControlKeys(1, buffer)
+	press(cleObj, buffer...)
 	this.So(cleObj.cursorPosition, should.Equal, len(cleObj.data))
 
 	cleObj.data = []rune("test command")
-	buffer = []byte{CONTROL_K, 0, 0}
+	buffer = []byte{CONTROL_K}
 	cleObj.cursorPosition = 5
-	cleObj.handleControlKeys(1, buffer)
+	press(cleObj, buffer...)
 	this.So(cleObj.data, should.Resemble, []rune("test "))
 
 	cleObj.data = []rune("test command")
-	buffer = []byte{CONTROL_N, 0, 0}
+	buffer = []byte{CONTROL_N}
 	cleObj.cursorPosition = 5
-	cleObj.handleControlKeys(1, buffer)
+	press(cleObj, buffer...)
 	this.So(cleObj.cursorPosition, should.Equal, 0)
 	this.So(len(cleObj.data), should.Equal, 0)
 }
@@ -336,7 +317,7 @@ func (this *CLEFixture) TestClearHistory() {
 	this.So(len(cleObj.history.commands), should.BeZeroValue)
 }
 
-func (this *CLEFixture) TestPrepareHistoryForWriting() {
+func (this *CLEFixture) TestTrimHistory() {
 	cleObj := NewCLE(TestMode(true))
 
 	cleObj.data = []rune("this is a history entry")
@@ -345,14 +326,14 @@ func (this *CLEFixture) TestPrepareHistoryForWriting() {
 	cleObj.data = []rune("this is a history entry 2")
 	cleObj.saveHistoryEntry()
 
-	history := cleObj.prepareHistoryForWriting()
-	this.So(len(history), should.Equal, 50)
-	this.So(bytes.Contains(history, []byte("entry 2")), should.BeTrue)
+	cleObj.trimHistory()
+	this.So(len(cleObj.history.commands), should.Equal, 2)
+	this.So(cleObj.history.currentPosition, should.Equal, 2)
 
 	cleObj.historyMax = 1
-	history = cleObj.prepareHistoryForWriting()
-	this.So(len(history), should.Equal, 26)
-	this.So(bytes.Contains(history, []byte("entry 2")), should.BeTrue)
+	cleObj.trimHistory()
+	this.So(cleObj.history.commands, should.Resemble, [][]byte{[]byte("this is a history entry 2")})
+	this.So(cleObj.history.currentPosition, should.Equal, 1)
 }
 
 func (this *CLEFixture) TestLoadHistory() {
@@ -378,22 +359,10 @@ func (this *CLEFixture) TestInsert() {
 	this.So(insert([]rune("café"), 2, 'X'), should.Resemble, []rune("caXfé"))
 }
 
-func (this *CLEFixture) TestRemove() {
-	data := []rune("abcdef")
-
-	this.So(remove(data, 2), should.Resemble, []rune("abdef"))
-	this.So(remove(data, 0), should.Resemble, []rune("bcdef"))
-	this.So(remove(data, 99), should.Resemble, []rune("abcdef"))
-
-	// Removing a multibyte rune removes the whole character.
-	this.So(remove([]rune("café"), 3), should.Resemble, []rune("caf"))
-}
-
-func (this *CLEFixture) TestHandleEnterKeyWithSearchModePrefix() {
+func (this *CLEFixture) TestAcceptLineWithSearchModePrefix() {
 	cleObj := NewCLE(TestMode(true))
 	cleObj.data = []rune(":search term")
-	handled := cleObj.handleEnterKey(1, []byte{ENTER_KEY, 0, 0})
-	this.So(handled, should.BeTrue)
+	this.So(press(cleObj, ENTER_KEY), should.Equal, errLineAccepted)
 	this.So(len(cleObj.data), should.BeZeroValue)
 }
 
@@ -458,39 +427,58 @@ func (this *CLEFixture) TestClearHistoryDeletesFile() {
 	this.So(os.IsNotExist(statErr), should.BeTrue)
 }
 
-func (this *CLEFixture) TestHandlePasteWithUnprintableBytes() {
+func (this *CLEFixture) TestKeysInOneReadWithControlCharacters() {
 	cleObj := NewCLE(TestMode(true))
-	cleObj.handlePaste([]byte{'a', 1, 'b'}) // 1 is a control char, should be skipped
-	this.So(cleObj.data, should.Resemble, []rune("ab"))
+	press(cleObj, 'a', CONTROL_A, 'b') // each key is handled in turn
+	this.So(cleObj.data, should.Resemble, []rune("ba"))
 }
 
-func (this *CLEFixture) TestHandlePasteWithAccentedCharacters() {
+func (this *CLEFixture) TestKeysInOneReadWithAccentedCharacters() {
 	cleObj := NewCLE(TestMode(true))
 
 	// "Á" is U+00C1, UTF-8: 0xC3 0x81 -- both bytes are >= 0x80.
-	cleObj.handlePaste([]byte("Á"))
+	press(cleObj, []byte("Á")...)
 	this.So(cleObj.data, should.Resemble, []rune("Á"))
 	this.So(string(cleObj.data), should.Equal, "Á")
 
-	// Accented characters mixed with ASCII and control bytes in one paste.
+	// Accented characters mixed with ASCII and unbound control keys in one read.
 	cleObj = NewCLE(TestMode(true))
-	cleObj.handlePaste([]byte("caf\xc3\xa9\x01!")) // "café" + control byte + "!"
+	press(cleObj, []byte("caf\xc3\xa9\x07!")...) // "café" + CTL-G + "!"
 	this.So(string(cleObj.data), should.Equal, "café!")
 }
 
-func (this *CLEFixture) TestHandlePasteCarriesIncompleteTrailingRune() {
-	cleObj := NewCLE(TestMode(true))
+func (this *CLEFixture) TestReadKeyAcrossReads() {
+	reader, writer := io.Pipe()
+	cleObj := NewCLE(TestMode(true), Input(reader))
+	this.So(cleObj.openTty(), should.BeNil)
+	defer cleObj.closeTty()
+
+	// A two-byte character ("é" = 0xC3 0xA9) and an escape sequence split across reads.
+	go func() {
+		writer.Write([]byte{'a', 0xC3})
+		writer.Write([]byte{0xA9, ESCAPE_KEY})
+		writer.Write([]byte{ARROW_KEY_INDICATOR})
+		writer.Write([]byte{'3', '~'})
+	}()
+
+	for _, expected := range []Key{{Code: 'a'}, {Code: 'é'}, {Code: KeyDelete}} {
+		key, err := cleObj.readKey()
+		this.So(err, should.BeNil)
+		this.So(key, should.Resemble, expected)
+	}
+}
 
-	// A two-byte character ("é" = 0xC3 0xA9) split across two reads: the first
-	// read ends mid-character, so its trailing byte must be carried over.
-	carry := cleObj.handlePaste([]byte{'a', 0xC3})
-	this.So(cleObj.data, should.Resemble, []rune("a"))
-	this.So(carry, should.Resemble, []byte{0xC3})
+func (this *CLEFixture) TestReadKeyEscapeTimeout() {
+	reader, writer := io.Pipe()
+	cleObj := NewCLE(TestMode(true), Input(reader), EscapeTimeout(time.Millisecond))
+	this.So(cleObj.openTty(), should.BeNil)
+	defer cleObj.closeTty()
+
+	go writer.Write([]byte{ESCAPE_KEY})
 
-	// The completing byte arrives with the next read, prepended to the carry.
-	carry = cleObj.handlePaste(append(carry, 0xA9))
-	this.So(string(cleObj.data), should.Equal, "aé")
-	this.So(carry, should.BeEmpty)
+	key, err := cleObj.readKey()
+	this.So(err, should.BeNil)
+	this.So(key, should.Resemble, Key{Code: ESCAPE_KEY})
 }
 
 func (this *CLEFixture) TestCursorMovementIsRuneAware() {
@@ -514,14 +502,14 @@ func (this *CLEFixture) TestDeleteIsRuneAware() {
 	// Backspace removes a whole multibyte character, not a single byte.
 	cleObj.data = []rune("ábé")
 	cleObj.cursorPosition = 2 // just after 'b'
-	cleObj.handleDeleteKey(1, []byte{DELETE_KEY, 0, 0})
+	press(cleObj, DELETE_KEY)
 	this.So(string(cleObj.data), should.Equal, "áé")
 	this.So(cleObj.cursorPosition, should.Equal, 1)
 
 	// Ctrl+D (delete forward) removes the whole multibyte character at cursor.
 	cleObj.data = []rune("áé")
 	cleObj.cursorPosition = 0
-	cleObj.handleControlKeys(1, []byte{CONTROL_D, 0, 0})
+	press(cleObj, CONTROL_D)
 	this.So(string(cleObj.data), should.Equal, "é")
 	this.So(cleObj.cursorPosition, should.Equal, 0)
 }
@@ -532,7 +520,7 @@ func (this *CLEFixture) TestWordDeleteIsRuneAware() {
 	// Word delete operates on characters, leaving surrounding multibyte text intact.
 	cleObj.data = []rune("café über")
 	cleObj.cursorPosition = len(cleObj.data) // past last char
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(string(cleObj.data), should.Equal, "café ")
 	this.So(cleObj.cursorPosition, should.Equal, 5)
 }
@@ -593,7 +581,7 @@ func (this *CLEFixture) TestHandleArrowKeysAltBackspace() {
 	cleObj.cursorPosition = 11 // past last char
 
 	// ESC DEL: Alt+Backspace deletes word to the left
-	cleObj.handleArrowKeys(2, []byte{ESCAPE_KEY, DELETE_KEY, 0, 0, 0, 0})
+	press(cleObj, ESCAPE_KEY, DELETE_KEY)
 	this.So(cleObj.data, should.Resemble, []rune("hello "))
 	this.So(cleObj.cursorPosition, should.Equal, 6)
 }
@@ -604,14 +592,14 @@ func (this *CLEFixture) TestHandleArrowKeysWordDeleteRight() {
 	cleObj.cursorPosition = 6 // on 'w'
 
 	// ESC d: readline/xterm-style Alt+D
-	cleObj.handleArrowKeys(2, []byte{ESCAPE_KEY, 'd', 0, 0, 0, 0})
+	press(cleObj, ESCAPE_KEY, 'd')
 	this.So(cleObj.data, should.Resemble, []rune("hello "))
 
 	cleObj.data = []rune("hello world")
 	cleObj.cursorPosition = 6
 
 	// macOS Terminal.app Option+D sends ∂ (U+2202, UTF-8: 0xE2 0x88 0x82)
-	cleObj.handleArrowKeys(3, []byte{0xE2, 0x88, 0x82, 0, 0, 0})
+	press(cleObj, 0xE2, 0x88, 0x82)
 	this.So(cleObj.data, should.Resemble, []rune("hello "))
 }
 
@@ -653,54 +641,260 @@ func (this *CLEFixture) TestHandleWordDeleteLeft() {
 	// Cursor in the middle of a word: delete chars to the left until space (char at cursor is not deleted)
 	cleObj.data = []rune("hello world")
 	cleObj.cursorPosition = 8 // on 'r'
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(cleObj.data, should.Resemble, []rune("hello rld"))
 	this.So(cleObj.cursorPosition, should.Equal, 6)
 
 	// Cursor past end: delete word to the left until space
 	cleObj.data = []rune("hello world")
 	cleObj.cursorPosition = 11 // past last char
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(cleObj.data, should.Resemble, []rune("hello "))
 	this.So(cleObj.cursorPosition, should.Equal, 6)
 
 	// Cursor at start of word (char to left is space): delete space and word to the left, char at cursor is not deleted
 	cleObj.data = []rune("hello world")
 	cleObj.cursorPosition = 6 // on 'w', data[5]==' '
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(cleObj.data, should.Resemble, []rune("world"))
 	this.So(cleObj.cursorPosition, should.BeZeroValue)
 
 	// Cursor past end after trailing space: delete space and word to the left
 	cleObj.data = []rune("hello ")
 	cleObj.cursorPosition = 6 // past trailing space
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(cleObj.data, should.BeEmpty)
 	this.So(cleObj.cursorPosition, should.BeZeroValue)
 
 	// No whitespace to left: delete back to beginning of line, char at cursor not deleted
 	cleObj.data = []rune("hello")
 	cleObj.cursorPosition = 3 // on 'l'
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(cleObj.data, should.Resemble, []rune("lo"))
 	this.So(cleObj.cursorPosition, should.BeZeroValue)
 
 	// Cursor at position 0: nothing to the left, nothing deleted
 	cleObj.data = []rune("hello world")
 	cleObj.cursorPosition = 0 // on 'h'
-	cleObj.handleControlKeys(1, []byte{CONTROL_W, 0, 0})
+	press(cleObj, CONTROL_W)
 	this.So(cleObj.data, should.Resemble, []rune("hello world"))
 	this.So(cleObj.cursorPosition, should.BeZeroValue)
 }
 
-func (this *CLEFixture) TestHandleControlKeysUnrecognized() {
+func (this *CLEFixture) TestControlKeysUnrecognized() {
 	cleObj := NewCLE(TestMode(true))
 	cleObj.data = []rune("some data")
 	cleObj.cursorPosition = 4
 
-	// CTRL+C (3) satisfies isControlKey but has no case in the switch
-	handled := cleObj.handleControlKeys(1, []byte{3, 0, 0})
-	this.So(handled, should.BeTrue)
+	// CTRL+O (15) is a control key with no binding
+	this.So(press(cleObj, 15), should.BeNil)
 	this.So(cleObj.data, should.Resemble, []rune("some data"))
 	this.So(cleObj.cursorPosition, should.Equal, 4)
 }
+
+func (this *CLEFixture) TestEndOfInput() {
+	cleObj := NewCLE(TestMode(true))
+
+	this.So(press(cleObj, CONTROL_D), should.Equal, ErrEOF)
+	this.So(press(cleObj, CONTROL_A), should.BeNil)
+
+	cleObj.data = []rune("some data")
+	this.So(press(cleObj, CONTROL_D), should.BeNil)
+}
+
+func (this *CLEFixture) TestReadError() {
+	this.So(readError(io.EOF), should.Equal, ErrEOF)
+	this.So(readError(&os.PathError{Op: "read", Path: TTY, Err: syscall.EINTR}), should.Equal, ErrInterrupted)
+
+	err := &os.PathError{Op: "read", Path: TTY, Err: syscall.EIO}
+	this.So(readError(err), should.Equal, err)
+}
+
+func (this *CLEFixture) TestReadLineFromConfiguredInputAndOutput() {
+	output := new(bytes.Buffer)
+	terminal := new(FakeTerminal)
+	cleObj := NewCLE(
+		Input(iotest.OneByteReader(strings.NewReader("abc\r"))),
+		Output(output),
+		TerminalDevice(terminal),
+	)
+
+	line, err := cleObj.ReadLine("> ")
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "abc")
+	this.So(output.String(), should.ContainSubstring, "> abc")
+	this.So(terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+}
+
+func (this *CLEFixture) TestReadLineEnablesBracketedPaste() {
+	output := new(bytes.Buffer)
+	cleObj := NewCLE(Input(strings.NewReader("a\x1b[200~b\r\nc\x1b[201~\r")), Output(output))
+
+	line, err := cleObj.ReadLine("> ")
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "ab c")
+	this.So(output.String(), should.StartWith, BRACKETED_PASTE_ON)
+	this.So(output.String(), should.EndWith, BRACKETED_PASTE_OFF)
+
+	output.Reset()
+	cleObj = NewCLE(Input(strings.NewReader("abc\r")), Output(output), BracketedPaste(false))
+	_, _ = cleObj.ReadLine("> ")
+	this.So(output.String(), should.NotContainSubstring, BRACKETED_PASTE_ON)
+	this.So(output.String(), should.NotContainSubstring, BRACKETED_PASTE_OFF)
+}
+
+func (this *CLEFixture) TestPasteInsertsTextAsOneEdit() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("ab")
+	cleObj.cursorPosition = 1
+
+	press(cleObj, []byte("\x1b[200~one\ttwo\x01\r\n\x1b[201~")...)
+	this.So(string(cleObj.data), should.Equal, "aone two b")
+	this.So(cleObj.cursorPosition, should.Equal, 9)
+
+	cleObj.handleKey(Ctrl('_'))
+	this.So(string(cleObj.data), should.Equal, "ab")
+}
+
+func (this *CLEFixture) TestPasteKeepsLineBreaksInMultiLineInput() {
+	cleObj := NewCLE(TestMode(true), Validation(BalancedValidator()))
+
+	press(cleObj, []byte("\x1b[200~(one\r\ntwo)\x1b[201~")...)
+	this.So(string(cleObj.data), should.Equal, "(one\ntwo)")
+}
+
+func (this *CLEFixture) TestReadLineReportsEndOfInput() {
+	cleObj := NewCLE(Input(strings.NewReader("")), Output(io.Discard))
+
+	line, err := cleObj.ReadLine("> ")
+	this.So(err, should.Equal, ErrEOF)
+	this.So(line, should.BeEmpty)
+}
+
+func (this *CLEFixture) TestReadLineReportsRawModeFailure() {
+	terminal := &FakeTerminal{rawModeErr: errors.New("not a terminal")}
+	cleObj := NewCLE(Input(strings.NewReader("abc\r")), Output(io.Discard), TerminalDevice(terminal))
+
+	_, err := cleObj.ReadLine("> ")
+	this.So(errors.Is(err, ErrNoTerminal), should.BeTrue)
+	this.So(terminal.calls, should.Resemble, []string{"RawMode"})
+}
+
+func (this *CLEFixture) TestAcceptLineContinuation() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("say 'hello")
+	cleObj.cursorPosition = len(cleObj.data)
+	this.So(press(cleObj, ENTER_KEY), should.Equal, errLineAccepted) // no validator
+
+	cleObj = NewCLE(TestMode(true), Validation(BalancedValidator()))
+	cleObj.data = []rune("say 'hello")
+	cleObj.cursorPosition = len(cleObj.data)
+	this.So(press(cleObj, ENTER_KEY), should.BeNil)
+	this.So(string(cleObj.data), should.Equal, "say 'hello\n")
+	this.So(cleObj.cursorPosition, should.Equal, len(cleObj.data))
+
+	cleObj.data = append(cleObj.data, []rune("world'")...)
+	this.So(press(cleObj, ENTER_KEY), should.Equal, errLineAccepted)
+}
+
+func (this *CLEFixture) TestBalancedValidator() {
+	validator := BalancedValidator()
+	this.So(validator.Validate([]rune("select (1, 2)")), should.BeTrue)
+	this.So(validator.Validate([]rune("select (1, [2")), should.BeFalse)
+	this.So(validator.Validate([]rune("say \"(\"")), should.BeTrue)
+	this.So(validator.Validate([]rune("say 'it")), should.BeFalse)
+}
+
+func (this *CLEFixture) TestHandledLineUpAndDown() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("first\nab\nthird")
+	cleObj.cursorPosition = 14 // on 'r' in "third"
+
+	this.So(cleObj.handledLineUp(), should.BeTrue)
+	this.So(cleObj.cursorPosition, should.Equal, 8) // end of "ab"
+	this.So(cleObj.handledLineUp(), should.BeTrue)
+	this.So(cleObj.cursorPosition, should.Equal, 2)
+	this.So(cleObj.handledLineUp(), should.BeFalse)
+
+	this.So(cleObj.handledLineDown(), should.BeTrue)
+	this.So(cleObj.cursorPosition, should.Equal, 8)
+	this.So(cleObj.handledLineDown(), should.BeTrue)
+	this.So(cleObj.cursorPosition, should.Equal, 11)
+	this.So(cleObj.handledLineDown(), should.BeFalse)
+}
+
+////////////////////////////////////////////
+
+// lineEditing edits the line of a CLE key by key and checks it, for the
+// fixtures of the editing commands, which embed it alongside *gunit.Fixture.
+type lineEditing struct {
+	fixture *gunit.Fixture
+	cle     *CLE
+}
+
+func newLineEditing(fixture *gunit.Fixture, cleObj *CLE) *lineEditing {
+	return &lineEditing{fixture: fixture, cle: cleObj}
+}
+
+// typeKeys handles the keys as if typed one at a time.
+func (this *lineEditing) typeKeys(keys ...Key) {
+	for _, key := range keys {
+		this.fixture.So(this.cle.handleKey(key), should.BeNil)
+	}
+}
+
+// typeText types the characters of the text, ESC (\x1b) being the Escape key.
+func (this *lineEditing) typeText(text string) {
+	for _, r := range text {
+		if r == '\x1b' {
+			this.typeKeys(Key{Code: ESCAPE_KEY})
+		} else {
+			this.typeKeys(Key{Code: r})
+		}
+	}
+}
+
+func (this *lineEditing) setLine(data string, cursor int) {
+	this.cle.data = []rune(data)
+	this.cle.cursorPosition = cursor
+}
+
+func (this *lineEditing) assertLine(data string, cursor int) {
+	this.fixture.So(string(this.cle.data), should.Equal, data)
+	this.fixture.So(this.cle.cursorPosition, should.Equal, cursor)
+}
+
+// press handles the keys in work as if read from the terminal in one read.
+func press(cleObj *CLE, work ...byte) error {
+	cleObj.decoder.write(work)
+	for {
+		key, decoded := cleObj.decoder.decode(true)
+		if !decoded {
+			return nil
+		}
+		if err := cleObj.handleKey(key); err != nil {
+			return err
+		}
+	}
+}
+
+type FakeTerminal struct {
+	calls      []string
+	rawModeErr error
+	columns    int
+	rows       int
+}
+
+func (this *FakeTerminal) RawMode() error {
+	this.calls = append(this.calls, "RawMode")
+	return this.rawModeErr
+}
+
+func (this *FakeTerminal) Restore() error {
+	this.calls = append(this.calls, "Restore")
+	return nil
+}
+
+func (this *FakeTerminal) Size() (columns, rows int, err error) {
+	return this.columns, this.rows, nil
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
-	"bytes"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/smartystreets/cle"
 )
@@ -13,9 +15,19 @@ func main() {
 	)
 
 	for {
-		data := commandLineEditor.ReadInput("Enter string: ")
+		line, err := commandLineEditor.ReadLine("Enter string: ")
 		commandLineEditor.SaveHistory()
-		if len(data) == 1 && bytes.ToLower(data)[0] == 'q' {
+		if errors.Is(err, cle.ErrInterrupted) {
+			continue // CTL-C abandons the line, as in a shell
+		}
+		if errors.Is(err, cle.ErrEOF) {
+			break
+		}
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		if strings.ToLower(line) == "q" {
 			break
 		}
 	}
@@ -0,0 +1,197 @@
+package cle
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Completer supplies the candidates for completing the input at the cursor.
+type Completer interface {
+	// Complete returns the candidates for input at cursor along with the span
+	// of input, [start:end], that a candidate replaces.
+	Complete(input []rune, cursor int) (candidates []string, start, end int)
+}
+
+// CompleterFunc adapts an ordinary function to the Completer interface.
+type CompleterFunc func(input []rune, cursor int) (candidates []string, start, end int)
+
+func (this CompleterFunc) Complete(input []rune, cursor int) (candidates []string, start, end int) {
+	return this(input, cursor)
+}
+
+// WordCompleter completes the word left of the cursor from a fixed list of words.
+func WordCompleter(words ...string) Completer {
+	return CompleterFunc(func(input []rune, cursor int) (candidates []string, start, end int) {
+		start = wordStart(input, cursor)
+		prefix := string(input[start:cursor])
+		for _, word := range words {
+			if strings.HasPrefix(word, prefix) {
+				candidates = append(candidates, word)
+			}
+		}
+		return candidates, start, cursor
+	})
+}
+
+// PathCompleter completes the file path left of the cursor. Directories are
+// completed with a trailing separator so that completion can continue into them.
+func PathCompleter() Completer {
+	return CompleterFunc(func(input []rune, cursor int) (candidates []string, start, end int) {
+		start = wordStart(input, cursor)
+		word := string(input[start:cursor])
+		directory, prefix := filepath.Split(word)
+		entries, err := os.ReadDir(orDefault(directory, "."))
+		if err != nil {
+			return nil, start, cursor
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+				continue
+			}
+			if entry.IsDir() {
+				name += string(filepath.Separator)
+			}
+			candidates = append(candidates, directory+name)
+		}
+		sort.Strings(candidates)
+		return candidates, start, cursor
+	})
+}
+
+// completion tracks consecutive presses of the TAB key (the Complete action): the first inserts the
+// longest common prefix of the candidates, the second lists them and every
+// further press cycles through them.
+type completion struct {
+	active     bool
+	listed     bool
+	candidates []string
+	index      int // the candidate shown while cycling
+	start      int // the span of data holding the completion
+	end        int
+}
+
+// complete completes the input at the cursor. The completion ends with any
+// other key, so that the next TAB starts a new one.
+func (this *CLE) complete() error {
+	if this.completer == nil {
+		return nil
+	}
+
+	switch {
+	case !this.completion.active:
+		this.startCompletion()
+	case !this.completion.listed:
+		this.completion.listed = true
+		this.listCompletions()
+	default:
+		this.completion.index = (this.completion.index + 1) % len(this.completion.candidates)
+		this.replaceCompletion(this.completion.candidates[this.completion.index])
+	}
+	this.repaint()
+	return nil
+}
+
+func (this *CLE) startCompletion() {
+	candidates, start, end := this.completer.Complete(this.data, this.cursorPosition)
+	if len(candidates) == 0 {
+		return
+	}
+	start, end = clamp(start, 0, len(this.data)), clamp(end, 0, len(this.data))
+	if start > end {
+		start = end
+	}
+
+	this.completion = completion{candidates: candidates, index: -1, start: start, end: end}
+	if len(candidates) == 1 {
+		this.replaceCompletion(candidates[0])
+		this.completion = completion{}
+		return
+	}
+	this.completion.active = true
+	if prefix := commonPrefix(candidates); utf8.RuneCountInString(prefix) > end-start {
+		this.replaceCompletion(prefix)
+	}
+}
+
+func (this *CLE) replaceCompletion(text string) {
+	replacement := []rune(text)
+	data := make([]rune, 0, len(this.data)+len(replacement))
+	data = append(data, this.data[:this.completion.start]...)
+	data = append(data, replacement...)
+	data = append(data, this.data[this.completion.end:]...)
+	this.data = data
+	this.completion.end = this.completion.start + len(replacement)
+	this.cursorPosition = this.completion.end
+}
+
+func (this *CLE) listCompletions() {
+	if this.testMode {
+		return
+	}
+
+	width := 0
+	for _, candidate := range this.completion.candidates {
+		if length := displayWidth(candidate); length > width {
+			width = length
+		}
+	}
+	width += 2
+	perRow := this.terminalWidth() / width
+	if perRow < 1 {
+		perRow = 1
+	}
+
+	this.crlf()
+	for i, candidate := range this.completion.candidates {
+		this.write(candidate)
+		if (i+1)%perRow == 0 || i == len(this.completion.candidates)-1 {
+			this.crlf()
+		} else {
+			this.write(strings.Repeat(" ", width-displayWidth(candidate)))
+		}
+	}
+}
+
+////////////////////////////////////////////
+
+func wordStart(input []rune, cursor int) int {
+	start := cursor
+	for start > 0 && input[start-1] != ' ' {
+		start--
+	}
+	return start
+}
+
+func commonPrefix(candidates []string) string {
+	prefix := []rune(candidates[0])
+	for _, candidate := range candidates[1:] {
+		runes := []rune(candidate)
+		i := 0
+		for i < len(prefix) && i < len(runes) && prefix[i] == runes[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return string(prefix)
+}
+
+func clamp(value, low, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
+
+func orDefault(value, defaultValue string) string {
+	if len(value) == 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,113 @@
+package cle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestCompletionFixture(t *testing.T) {
+	gunit.Run(new(CompletionFixture), t)
+}
+
+type CompletionFixture struct {
+	*gunit.Fixture
+}
+
+func (this *CompletionFixture) TestTabIgnoredWithoutCompleter() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("sta")
+	cleObj.cursorPosition = 3
+
+	press(cleObj, TAB_KEY)
+	this.So(cleObj.data, should.Resemble, []rune("sta"))
+}
+
+func (this *CompletionFixture) TestSingleCandidateIsInserted() {
+	cleObj := NewCLE(TestMode(true), Completion(WordCompleter("status", "commit")))
+	cleObj.data = []rune("git sta -v")
+	cleObj.cursorPosition = 7
+
+	press(cleObj, TAB_KEY)
+	this.So(string(cleObj.data), should.Equal, "git status -v")
+	this.So(cleObj.cursorPosition, should.Equal, 10)
+	this.So(cleObj.completion.active, should.BeFalse)
+}
+
+func (this *CompletionFixture) TestCommonPrefixThenListThenCycle() {
+	cleObj := NewCLE(TestMode(true), Completion(WordCompleter("checkout", "cherry-pick", "commit")))
+	cleObj.data = []rune("c")
+	cleObj.cursorPosition = 1
+
+	press(cleObj, TAB_KEY) // all three candidates share only "c"
+	this.So(string(cleObj.data), should.Equal, "c")
+	this.So(cleObj.completion.listed, should.BeFalse)
+
+	cleObj.data = []rune("ch")
+	cleObj.cursorPosition = 2
+	cleObj.completion = completion{}
+	press(cleObj, TAB_KEY)
+	this.So(string(cleObj.data), should.Equal, "che")
+	this.So(cleObj.cursorPosition, should.Equal, 3)
+
+	press(cleObj, TAB_KEY)
+	this.So(cleObj.completion.listed, should.BeTrue)
+	this.So(string(cleObj.data), should.Equal, "che")
+
+	press(cleObj, TAB_KEY)
+	this.So(string(cleObj.data), should.Equal, "checkout")
+	press(cleObj, TAB_KEY)
+	this.So(string(cleObj.data), should.Equal, "cherry-pick")
+	press(cleObj, TAB_KEY)
+	this.So(string(cleObj.data), should.Equal, "checkout")
+	this.So(cleObj.cursorPosition, should.Equal, 8)
+}
+
+func (this *CompletionFixture) TestOtherKeyEndsCompletion() {
+	cleObj := NewCLE(TestMode(true), Completion(WordCompleter("checkout", "cherry-pick")))
+	cleObj.data = []rune("ch")
+	cleObj.cursorPosition = 2
+
+	press(cleObj, TAB_KEY)
+	this.So(cleObj.completion.active, should.BeTrue)
+
+	press(cleObj, 'x')
+	this.So(cleObj.completion.active, should.BeFalse)
+	this.So(string(cleObj.data), should.Equal, "chex")
+}
+
+func (this *CompletionFixture) TestCompleterSpanIsClamped() {
+	completer := CompleterFunc(func(input []rune, cursor int) ([]string, int, int) {
+		return []string{"everything"}, -5, 99
+	})
+	cleObj := NewCLE(TestMode(true), Completion(completer))
+	cleObj.data = []rune("some")
+	cleObj.cursorPosition = 2
+
+	press(cleObj, TAB_KEY)
+	this.So(string(cleObj.data), should.Equal, "everything")
+}
+
+func (this *CompletionFixture) TestPathCompleter() {
+	directory, err := os.MkdirTemp("", "cle-completion-test-*")
+	this.So(err, should.BeNil)
+	defer os.RemoveAll(directory)
+	this.So(os.Mkdir(filepath.Join(directory, "folder"), 0755), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(directory, "file.txt"), nil, 0644), should.BeNil)
+	this.So(os.WriteFile(filepath.Join(directory, ".hidden"), nil, 0644), should.BeNil)
+
+	input := []rune("cat " + directory + "/f")
+	candidates, start, end := PathCompleter().Complete(input, len(input))
+	this.So(candidates, should.Resemble, []string{directory + "/file.txt", directory + "/folder/"})
+	this.So(start, should.Equal, 4)
+	this.So(end, should.Equal, len(input))
+}
+
+func (this *CompletionFixture) TestCommonPrefix() {
+	this.So(commonPrefix([]string{"checkout", "cherry"}), should.Equal, "che")
+	this.So(commonPrefix([]string{"café", "cafés"}), should.Equal, "café")
+	this.So(commonPrefix([]string{"abc", "xyz"}), should.BeEmpty)
+}
@@ -0,0 +1,43 @@
+package cle
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ReadInputContext displays the prompt and returns the line entered by the
+// user, like ReadLine, unless the context is done first (e.g. cancelled, or
+// past its deadline). Then the partially typed line is cleared from the
+// display, the terminal is restored, and the error is ctx.Err().
+func (this *CLE) ReadInputContext(ctx context.Context, prompt string) ([]byte, error) {
+	return this.readLine(ctx, prompt)
+}
+
+// watchContext interrupts the read when the context is done, until the
+// returned function is called, which waits for the interruption to finish.
+func (this *CLE) watchContext(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt32(&this.aborted, 1)
+			this.reader.interrupt()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// cancel erases the prompt and input from the display, for a read ended by
+// its context.
+func (this *CLE) cancel(ctx context.Context) error {
+	this.erase()
+	return ctx.Err()
+}
@@ -0,0 +1,88 @@
+package cle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestContextFixture(t *testing.T) {
+	gunit.Run(new(ContextFixture), t)
+}
+
+type ContextFixture struct {
+	*gunit.Fixture
+	terminal *FakeTerminal
+	output   *bytes.Buffer
+	writer   *io.PipeWriter
+	cle      *CLE
+}
+
+func (this *ContextFixture) Setup() {
+	this.terminal = new(FakeTerminal)
+	this.output = new(bytes.Buffer)
+	var reader *io.PipeReader
+	reader, this.writer = io.Pipe()
+	this.cle = NewCLE(Input(reader), Output(this.output), TerminalDevice(this.terminal), BracketedPaste(false))
+}
+
+func (this *ContextFixture) Teardown() {
+	_ = this.writer.Close()
+}
+
+func (this *ContextFixture) TestCancelEndsRead() {
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		_, err := this.cle.ReadInputContext(ctx, "> ")
+		result <- err
+	}()
+	_, _ = this.writer.Write([]byte("abc"))
+
+	cancel()
+	this.So(<-result, should.Equal, context.Canceled)
+	this.So(this.terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+	this.So(this.output.String(), should.EndWith, "\r\x1b[J")
+}
+
+func (this *ContextFixture) TestDeadline() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	line, err := this.cle.ReadInputContext(ctx, "> ")
+	this.So(line, should.BeNil)
+	this.So(err, should.Equal, context.DeadlineExceeded)
+	this.So(this.terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+}
+
+func (this *ContextFixture) TestContextDoneBeforeRead() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := this.cle.ReadInputContext(ctx, "> ")
+	this.So(err, should.Equal, context.Canceled)
+	this.So(this.terminal.calls, should.BeEmpty)
+}
+
+func (this *ContextFixture) TestLineEnteredBeforeTheDeadline() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	go func() { _, _ = this.writer.Write([]byte("abc\r")) }()
+
+	line, err := this.cle.ReadInputContext(ctx, "> ")
+	this.So(string(line), should.Equal, "abc")
+	this.So(err, should.BeNil)
+}
+
+func (this *ContextFixture) TestCancelClearsWrappedInput() {
+	this.cle.rendered = rendering{cursorRow: 2, rows: 3}
+
+	this.So(this.cle.cancel(context.Background()), should.BeNil)
+	this.So(this.output.String(), should.Equal, "\x1b[2A\r\x1b[J")
+	this.So(this.cle.rendered, should.Resemble, rendering{})
+}
@@ -4,8 +4,8 @@ go 1.17
 
 require (
 	github.com/pkg/term v1.1.0
+	github.com/rivo/uniseg v0.4.7
 	github.com/smarty/assertions v1.15.1
 	github.com/smarty/gunit v1.5.0
+	golang.org/x/sys v0.11.0
 )
-
-require golang.org/x/sys v0.11.0 // indirect
@@ -0,0 +1,54 @@
+package cle
+
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// graphemes splits data into grapheme clusters, the characters the user
+// sees: e.g. "e" followed by a combining acute accent, or an emoji made of
+// several code points. It returns the number of runes and the display width
+// in columns (2 for East Asian wide characters) of each cluster.
+func graphemes(data []rune) (lengths, widths []int) {
+	rest, state := string(data), -1
+	for len(rest) > 0 {
+		var cluster string
+		var boundaries int
+		cluster, rest, boundaries, state = uniseg.StepString(rest, state)
+		lengths = append(lengths, utf8.RuneCountInString(cluster))
+		widths = append(widths, boundaries>>uniseg.ShiftWidth)
+	}
+	return lengths, widths
+}
+
+// graphemeLeft returns the position of the grapheme cluster before position.
+func graphemeLeft(data []rune, position int) int {
+	lengths, _ := graphemes(data)
+	start := 0
+	for _, length := range lengths {
+		if start+length >= position {
+			break
+		}
+		start += length
+	}
+	return min(start, position)
+}
+
+// graphemeRight returns the position following the grapheme cluster at position.
+func graphemeRight(data []rune, position int) int {
+	lengths, _ := graphemes(data)
+	end := 0
+	for _, length := range lengths {
+		end += length
+		if end > position {
+			return end
+		}
+	}
+	return len(data)
+}
+
+// displayWidth returns the number of columns text takes up on the screen.
+func displayWidth(text string) int {
+	return uniseg.StringWidth(text)
+}
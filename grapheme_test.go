@@ -0,0 +1,89 @@
+package cle
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestGraphemeFixture(t *testing.T) {
+	gunit.Run(new(GraphemeFixture), t)
+}
+
+type GraphemeFixture struct {
+	*gunit.Fixture
+}
+
+func (this *GraphemeFixture) TestGraphemes() {
+	lengths, widths := graphemes([]rune("ae\u0301日\U0001F44D\U0001F3FD"))
+	this.So(lengths, should.Resemble, []int{1, 2, 1, 2})
+	this.So(widths, should.Resemble, []int{1, 1, 2, 2})
+
+	lengths, widths = graphemes(nil)
+	this.So(lengths, should.BeEmpty)
+	this.So(widths, should.BeEmpty)
+}
+
+func (this *GraphemeFixture) TestGraphemeLeftAndRight() {
+	data := []rune("ae\u0301日") // "é" is "e" and a combining accent: positions 0, 1, 3, 4
+	for _, test := range []struct{ position, left, right int }{
+		{0, 0, 1},
+		{1, 0, 3},
+		{2, 1, 3}, // inside "é"
+		{3, 1, 4},
+		{4, 3, 4},
+	} {
+		this.So(graphemeLeft(data, test.position), should.Equal, test.left)
+		this.So(graphemeRight(data, test.position), should.Equal, test.right)
+	}
+}
+
+func (this *GraphemeFixture) TestCursorMovesOverWholeGraphemes() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("e\u0301\U0001F44D\U0001F3FD")
+	cleObj.cursorPosition = len(cleObj.data)
+
+	press(cleObj, ESCAPE_KEY, ARROW_KEY_INDICATOR, LEFT_ARROW)
+	this.So(cleObj.cursorPosition, should.Equal, 2)
+	press(cleObj, ESCAPE_KEY, ARROW_KEY_INDICATOR, LEFT_ARROW)
+	this.So(cleObj.cursorPosition, should.Equal, 0)
+	press(cleObj, ESCAPE_KEY, ARROW_KEY_INDICATOR, RIGHT_ARROW)
+	this.So(cleObj.cursorPosition, should.Equal, 2)
+}
+
+func (this *GraphemeFixture) TestDeletionRemovesWholeGraphemes() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("e\u0301\U0001F44D\U0001F3FDx")
+	cleObj.cursorPosition = 4
+
+	press(cleObj, DELETE_KEY) // backspace
+	this.So(string(cleObj.data), should.Equal, "e\u0301x")
+	this.So(cleObj.cursorPosition, should.Equal, 2)
+
+	cleObj.cursorPosition = 0
+	press(cleObj, CONTROL_D)
+	this.So(string(cleObj.data), should.Equal, "x")
+
+	cleObj.data = []rune("日e\u0301")
+	cleObj.cursorPosition = 1
+	press(cleObj, []byte("\x1b[3~")...) // Delete
+	this.So(string(cleObj.data), should.Equal, "日")
+}
+
+func (this *GraphemeFixture) TestViMotionsOverWholeGraphemes() {
+	cleObj := NewCLE(TestMode(true), ViMode(true))
+	cleObj.resetVi()
+	for _, r := range "ae\u0301" {
+		cleObj.handleKey(Key{Code: r})
+	}
+	cleObj.handleKey(Key{Code: ESCAPE_KEY})
+	this.So(cleObj.cursorPosition, should.Equal, 1)
+
+	cleObj.handleKey(Key{Code: 'h'})
+	this.So(cleObj.cursorPosition, should.Equal, 0)
+	cleObj.handleKey(Key{Code: 'l'})
+	this.So(cleObj.cursorPosition, should.Equal, 1)
+	cleObj.handleKey(Key{Code: 'x'})
+	this.So(string(cleObj.data), should.Equal, "a")
+}
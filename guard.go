@@ -0,0 +1,120 @@
+package cle
+
+import (
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+// Restore returns the terminal to the mode it was in before the read in
+// progress, if there is one, and ends the read with ErrInterrupted. It may be
+// called from any goroutine, e.g. a signal handler of the application.
+func (this *CLE) Restore() error {
+	this.terminalLock.Lock()
+	defer this.terminalLock.Unlock()
+
+	if !this.rawMode {
+		return nil
+	}
+	atomic.StoreInt32(&this.aborted, 1)
+	this.reader.interrupt()
+	return this.leaveRawModeLocked()
+}
+
+// Close restores the terminal, like Restore, after which reads fail with
+// ErrClosed. It also stops reading the configured input (see Input).
+func (this *CLE) Close() error {
+	atomic.StoreInt32(&this.closed, 1)
+	defer this.stopInputPump()
+	return this.Restore()
+}
+
+func (this *CLE) stopInputPump() {
+	this.terminalLock.Lock()
+	defer this.terminalLock.Unlock()
+
+	if this.pump != nil {
+		this.pump.stop()
+	}
+}
+
+// startedInputPump starts reading the configured input, unless the CLE is
+// closed, reporting whether it is being read.
+func (this *CLE) startedInputPump() bool {
+	this.terminalLock.Lock()
+	defer this.terminalLock.Unlock()
+
+	if atomic.LoadInt32(&this.closed) != 0 {
+		return false
+	}
+	if this.pump == nil {
+		this.pump = startInputPump(this.input)
+	}
+	return true
+}
+
+// enterRawMode switches the terminal to raw mode, and to bracketed paste
+// mode, for reading.
+func (this *CLE) enterRawMode() error {
+	this.terminalLock.Lock()
+	defer this.terminalLock.Unlock()
+
+	if this.control != nil {
+		if err := this.control.RawMode(); err != nil {
+			return err
+		}
+	}
+	if this.bracketedPaste {
+		this.write(BRACKETED_PASTE_ON)
+	}
+	this.rawMode = true
+	return nil
+}
+
+// leaveRawMode returns the terminal to the mode it was in before
+// enterRawMode, unless that has been done already (e.g. by Restore).
+func (this *CLE) leaveRawMode() error {
+	this.terminalLock.Lock()
+	defer this.terminalLock.Unlock()
+	return this.leaveRawModeLocked()
+}
+
+func (this *CLE) leaveRawModeLocked() error {
+	if !this.rawMode {
+		return nil
+	}
+	this.rawMode = false
+	if this.bracketedPaste {
+		this.write(BRACKETED_PASTE_OFF)
+	}
+	if this.control == nil {
+		return nil
+	}
+	return this.control.Restore()
+}
+
+// restoreOnPanic restores the terminal before a panic during a read (e.g. in
+// a Completer) unwinds any further, so that the user's shell is usable
+// whatever becomes of the panic.
+func (this *CLE) restoreOnPanic() {
+	if r := recover(); r != nil {
+		this.handleError(this.leaveRawMode())
+		panic(r)
+	}
+}
+
+// guardTerminal restores the terminal if the process is terminated (SIGTERM)
+// or hung up (SIGHUP) during the read, and then delivers the signal again to
+// end the process as usual, unless the application watches the signal itself
+// (see HandledSignals): it has received the signal already.
+func (this *CLE) guardTerminal() (stop func()) {
+	if this.control == nil {
+		return func() {}
+	}
+	return watchSignalOnce(func(received os.Signal) {
+		this.handleError(this.Restore())
+		if !this.handledSignals {
+			resignal(received)
+		}
+	}, syscall.SIGTERM, syscall.SIGHUP)
+}
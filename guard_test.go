@@ -0,0 +1,182 @@
+package cle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestGuardFixture(t *testing.T) {
+	gunit.Run(new(GuardFixture), t)
+}
+
+type GuardFixture struct {
+	*gunit.Fixture
+	terminal *FakeTerminal
+}
+
+func (this *GuardFixture) Setup() {
+	this.terminal = new(FakeTerminal)
+}
+
+// readInBackground starts reading a line from input that never ends, and
+// returns once the read has switched the terminal to raw mode.
+func (this *GuardFixture) readInBackground(cleObj *CLE) chan error {
+	result := make(chan error, 1)
+	go func() {
+		_, err := cleObj.ReadLine("> ")
+		result <- err
+	}()
+	for {
+		cleObj.terminalLock.Lock()
+		rawMode := cleObj.rawMode
+		cleObj.terminalLock.Unlock()
+		if rawMode {
+			return result
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (this *GuardFixture) TestRestoreEndsRead() {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	cleObj := NewCLE(Input(reader), Output(io.Discard), TerminalDevice(this.terminal))
+	result := this.readInBackground(cleObj)
+
+	this.So(cleObj.Restore(), should.BeNil)
+	this.So(<-result, should.Equal, ErrInterrupted)
+	this.So(this.terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+	this.So(cleObj.Restore(), should.BeNil) // no read in progress
+	this.So(this.terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+}
+
+func (this *GuardFixture) TestClose() {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	cleObj := NewCLE(Input(reader), Output(io.Discard), TerminalDevice(this.terminal))
+	result := this.readInBackground(cleObj)
+
+	this.So(cleObj.Close(), should.BeNil)
+	this.So(<-result, should.Equal, ErrInterrupted)
+	_, err := cleObj.ReadLine("> ")
+	this.So(err, should.Equal, ErrClosed)
+	this.So(this.terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+}
+
+func (this *GuardFixture) TestRestoreOnPanic() {
+	cleObj := NewCLE(
+		Input(strings.NewReader("abc\t")),
+		Output(io.Discard),
+		TerminalDevice(this.terminal),
+		Completion(CompleterFunc(func([]rune, int) ([]string, int, int) { panic("boom") })),
+	)
+
+	recovered := func() (recovered interface{}) {
+		defer func() { recovered = recover() }()
+		_, _ = cleObj.ReadLine("> ")
+		return nil
+	}()
+	this.So(recovered, should.Equal, "boom")
+	this.So(this.terminal.calls, should.Resemble, []string{"RawMode", "Restore"})
+}
+
+func (this *GuardFixture) TestTerminationSignalEndsProcessAfterRestoring() {
+	output, err := this.terminateGuardedRead("unhandled")
+	this.So(output, should.Resemble, []string{"Restore"})
+	this.So(err, should.HaveSameTypeAs, &exec.ExitError{})
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		status := exitErr.Sys().(syscall.WaitStatus)
+		this.So(status.Signaled(), should.BeTrue)
+		this.So(status.Signal(), should.Equal, syscall.SIGTERM)
+	}
+}
+
+func (this *GuardFixture) TestHandledTerminationSignalIsLeftToApplication() {
+	output, err := this.terminateGuardedRead("handled")
+	this.So(output, should.Resemble, []string{"Restore", "RECEIVED 1", "PASS"})
+	this.So(err, should.BeNil)
+}
+
+// terminateGuardedRead sends SIGTERM to a TestGuardedReadHelperProcess during
+// its read, returning what it prints afterwards and how it ended.
+func (this *GuardFixture) terminateGuardedRead(signals string) (output []string, err error) {
+	helper := exec.Command(os.Args[0], "-test.run=^TestGuardedReadHelperProcess$")
+	helper.Env = append(os.Environ(), "CLE_GUARDED_READ_HELPER="+signals)
+	stdout, err := helper.StdoutPipe()
+	this.So(err, should.BeNil)
+	this.So(helper.Start(), should.BeNil)
+	lines := bufio.NewScanner(stdout)
+	for lines.Scan() && lines.Text() != "READY" {
+	}
+
+	this.So(helper.Process.Signal(syscall.SIGTERM), should.BeNil)
+	for lines.Scan() {
+		output = append(output, lines.Text())
+	}
+	return output, helper.Wait()
+}
+
+// TestGuardedReadHelperProcess is the process that terminateGuardedRead
+// terminates during a read. With "handled" signals, it watches SIGTERM itself
+// and prints how many it received.
+func TestGuardedReadHelperProcess(t *testing.T) {
+	signals := os.Getenv("CLE_GUARDED_READ_HELPER")
+	if signals == "" {
+		t.Skip("run by terminateGuardedRead")
+	}
+	received := make(chan os.Signal, 2)
+	if signals == "handled" {
+		signal.Notify(received, syscall.SIGTERM)
+	}
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	cleObj := NewCLE(Input(reader), Output(io.Discard), TerminalDevice(printingTerminal{}), HandledSignals(signals == "handled"))
+	go func() { _, _ = cleObj.ReadLine("> ") }()
+	for !isDisplaying(cleObj) {
+		time.Sleep(time.Millisecond)
+	}
+	fmt.Println("READY")
+
+	if signals != "handled" {
+		time.Sleep(10 * time.Second)
+		fmt.Println("STILL ALIVE")
+		return
+	}
+	<-received
+	time.Sleep(200 * time.Millisecond)
+	fmt.Println("RECEIVED", 1+len(received))
+}
+
+func isDisplaying(cleObj *CLE) bool {
+	cleObj.displayLock.Lock()
+	defer cleObj.displayLock.Unlock()
+	return cleObj.displaying
+}
+
+// printingTerminal prints the calls to the terminal.
+type printingTerminal struct{}
+
+func (printingTerminal) RawMode() error {
+	fmt.Println("RawMode")
+	return nil
+}
+
+func (printingTerminal) Restore() error {
+	fmt.Println("Restore")
+	return nil
+}
+
+func (printingTerminal) Size() (columns, rows int, err error) {
+	return 80, 24, nil
+}
@@ -0,0 +1,74 @@
+package cle
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Highlighter styles the input as it is displayed, e.g. to colour keywords,
+// strings and invalid tokens. Styles take up no space on the screen, so the
+// cursor is placed as for the unstyled input.
+type Highlighter interface {
+	// Highlight returns the styled segments of input. Input outside the
+	// segments is displayed unstyled.
+	Highlight(input []rune) []Segment
+}
+
+// Segment styles the input from Start up to End (rune positions) with an
+// ANSI SGR sequence, e.g. "\x1b[1;34m" for bold blue.
+type Segment struct {
+	Start int
+	End   int
+	Style string
+}
+
+// HighlighterFunc adapts an ordinary function to the Highlighter interface.
+type HighlighterFunc func(input []rune) []Segment
+
+func (this HighlighterFunc) Highlight(input []rune) []Segment {
+	return this(input)
+}
+
+// KeywordHighlighter styles each word (run of letters, digits and
+// underscores) of the input that is one of keywords, ignoring case.
+func KeywordHighlighter(style string, keywords ...string) Highlighter {
+	return HighlighterFunc(func(input []rune) (segments []Segment) {
+		for start := 0; start < len(input); start++ {
+			if !isWordRune(input[start]) {
+				continue
+			}
+			end := start
+			for end < len(input) && isWordRune(input[end]) {
+				end++
+			}
+			for _, keyword := range keywords {
+				if strings.EqualFold(string(input[start:end]), keyword) {
+					segments = append(segments, Segment{Start: start, End: end, Style: style})
+					break
+				}
+			}
+			start = end
+		}
+		return segments
+	})
+}
+
+// highlight applies the styles of the highlighter, if there is one, to cells.
+func (this *CLE) highlight(cells []cell) {
+	if this.highlighter == nil {
+		return
+	}
+	for _, segment := range this.highlighter.Highlight(append([]rune(nil), this.data...)) {
+		start := clamp(segment.Start, 0, len(cells))
+		end := clamp(segment.End, start, len(cells))
+		for i := start; i < end; i++ {
+			cells[i].style = segment.Style
+		}
+	}
+}
+
+////////////////////////////////////////////
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,55 @@
+package cle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestHighlighterFixture(t *testing.T) {
+	gunit.Run(new(HighlighterFixture), t)
+}
+
+type HighlighterFixture struct {
+	*gunit.Fixture
+}
+
+const testBlue = "\x1b[34m"
+
+func (this *HighlighterFixture) TestKeywordHighlighter() {
+	highlighter := KeywordHighlighter(testBlue, "select", "from")
+
+	this.So(highlighter.Highlight([]rune("SELECT count(*) from selected")), should.Resemble, []Segment{
+		{Start: 0, End: 6, Style: testBlue},
+		{Start: 16, End: 20, Style: testBlue},
+	})
+	this.So(highlighter.Highlight([]rune("")), should.BeEmpty)
+}
+
+func (this *HighlighterFixture) TestHighlightedRendering() {
+	output := new(bytes.Buffer)
+	cleObj := NewCLE(Output(output), Highlighting(KeywordHighlighter(testBlue, "ab")))
+	cleObj.control = &FakeTerminal{columns: 20}
+	cleObj.prompt = "> "
+	cleObj.data = []rune("ab cd ab")
+	cleObj.cursorPosition = 4
+
+	cleObj.repaint()
+	// The styles take no space: the cursor is after "> ab c".
+	this.So(output.String(), should.Equal,
+		"\r\x1b[J> "+SGR_RESET+testBlue+"ab"+SGR_RESET+" cd "+SGR_RESET+testBlue+"ab"+SGR_RESET+"\r\x1b[6C")
+}
+
+func (this *HighlighterFixture) TestSegmentsOutsideTheInputAreClamped() {
+	output := new(bytes.Buffer)
+	cleObj := NewCLE(Output(output), Highlighting(HighlighterFunc(func(input []rune) []Segment {
+		return []Segment{{Start: -5, End: 1, Style: testBlue}, {Start: 2, End: 99, Style: testBlue}, {Start: 3, End: 2, Style: testBlue}}
+	})))
+	cleObj.prompt = "> "
+	cleObj.data = []rune("abc")
+
+	cleObj.repaint()
+	this.So(output.String(), should.ContainSubstring, SGR_RESET+testBlue+"a"+SGR_RESET+"b"+SGR_RESET+testBlue+"c"+SGR_RESET)
+}
@@ -0,0 +1,197 @@
+package cle
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// HistoryPolicy controls which commands are saved in the history, like bash's
+// HISTCONTROL (see HistoryControl). Policies are combined with |.
+type HistoryPolicy int
+
+const (
+	// IgnoreSpace leaves out commands beginning with a space.
+	IgnoreSpace HistoryPolicy = 1 << iota
+
+	// IgnoreDups leaves out a command the same as the command before it.
+	IgnoreDups
+
+	// EraseDups removes the earlier commands the same as a command entered,
+	// so that the history holds each command once, where it was last used.
+	EraseDups
+
+	// IgnoreBoth is IgnoreSpace and IgnoreDups.
+	IgnoreBoth = IgnoreSpace | IgnoreDups
+)
+
+// historyPattern matches a command to be left out of the history (see
+// IgnorePatterns); a glob matches the whole command or any word of it.
+type historyPattern struct {
+	regexp *regexp.Regexp
+	glob   bool
+}
+
+// compileIgnorePatterns compiles the patterns of IgnorePatterns: a pattern
+// between slashes is a regular expression, and any other a glob. A pattern
+// that cannot be compiled is left out, and its error kept for the next read
+// to return (see ReadLine).
+func (this *CLE) compileIgnorePatterns(patterns []string) (compiled []historyPattern) {
+	for _, pattern := range patterns {
+		if ignored, err := compileIgnorePattern(pattern); err != nil {
+			this.fail(err)
+		} else {
+			compiled = append(compiled, ignored)
+		}
+	}
+	return compiled
+}
+
+func compileIgnorePattern(pattern string) (historyPattern, error) {
+	expression, glob, err := "", false, error(nil)
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		expression = pattern[1 : len(pattern)-1]
+	} else {
+		expression, err = globExpression(pattern)
+		glob = true
+	}
+	var compiled *regexp.Regexp
+	if err == nil {
+		compiled, err = regexp.Compile(expression)
+	}
+	if err != nil {
+		return historyPattern{}, fmt.Errorf("cle: IgnorePatterns(%q): %w", pattern, err)
+	}
+	return historyPattern{regexp: compiled, glob: glob}, nil
+}
+
+// globExpression translates a glob to a regular expression matching the whole
+// of a text: * matches any characters (even /), ? any one character, [...] any
+// one character of the class ([!...] or [^...] any other), and \ quotes the
+// character after it.
+func globExpression(glob string) (string, error) {
+	expression := strings.Builder{}
+	expression.WriteString(`(?s)^`)
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			expression.WriteString(`.*`)
+		case '?':
+			expression.WriteString(`.`)
+		case '\\':
+			if i++; i == len(glob) {
+				return "", path.ErrBadPattern
+			}
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			class, length, err := globClass(glob[i+1:])
+			if err != nil {
+				return "", err
+			}
+			expression.WriteString(class)
+			i += length
+		default:
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expression.WriteString(`$`)
+	return expression.String(), nil
+}
+
+// globClass translates the character class at the start of the glob, just
+// after its [, returning the length of the glob it takes up, up to its ].
+func globClass(glob string) (class string, length int, err error) {
+	expression := strings.Builder{}
+	expression.WriteString(`[`)
+	i := 0
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		expression.WriteString(`^`)
+		i++
+	}
+	for first := i; i < len(glob); i++ {
+		switch {
+		case glob[i] == ']' && i > first:
+			expression.WriteString(`]`)
+			return expression.String(), i + 1, nil
+		case glob[i] == '\\':
+			if i++; i == len(glob) {
+				return "", 0, path.ErrBadPattern
+			}
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case glob[i] == '-':
+			expression.WriteString(`-`)
+		default:
+			expression.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return "", 0, path.ErrBadPattern
+}
+
+// ignoredByHistory reports whether the command is to be left out of the
+// history, given the entry that would be saved for it (see Redaction).
+func (this *CLE) ignoredByHistory(command string, entry []byte) bool {
+	if this.historyControl&IgnoreSpace != 0 && strings.HasPrefix(command, " ") {
+		return true
+	}
+	if this.historyControl&IgnoreDups != 0 && this.commandIsAlreadyPreviousEntryInHistory(entry) {
+		return true
+	}
+	for _, pattern := range this.historyIgnores {
+		if pattern.matches(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether a regular expression matches any part of the
+// command, or a glob the whole command or any word of it.
+func (this historyPattern) matches(command string) bool {
+	if !this.glob {
+		return this.regexp.MatchString(command)
+	}
+	for _, text := range append([]string{command}, strings.Fields(command)...) {
+		if this.regexp.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// erase removes every entry of the command from the history.
+func (this *CommandHistory) erase(command []byte) {
+	this.filter(func(i int) bool { return string(this.commands[i]) != string(command) })
+}
+
+// eraseDuplicates removes every entry of the history but the last of each
+// command.
+func (this *CommandHistory) eraseDuplicates() {
+	seen := make(map[string]bool)
+	keep := make([]bool, len(this.commands))
+	for i := len(this.commands) - 1; i >= 0; i-- {
+		keep[i] = !seen[string(this.commands[i])]
+		seen[string(this.commands[i])] = true
+	}
+	this.filter(func(i int) bool { return keep[i] })
+}
+
+// filter keeps the entries of the history for which keep reports true.
+func (this *CommandHistory) filter(keep func(i int) bool) {
+	kept, saved := 0, this.saved
+	for i := range this.commands {
+		if !keep(i) {
+			if i < saved {
+				this.saved--
+			}
+			continue
+		}
+		this.commands[kept] = this.commands[i]
+		if i < len(this.details) {
+			this.details[kept] = this.details[i]
+		}
+		kept++
+	}
+	this.commands = this.commands[:kept]
+	this.details = this.details[:min(kept, len(this.details))]
+}
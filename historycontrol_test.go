@@ -0,0 +1,106 @@
+package cle
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestHistoryControlFixture(t *testing.T) {
+	gunit.Run(new(HistoryControlFixture), t)
+}
+
+type HistoryControlFixture struct {
+	*gunit.Fixture
+}
+
+func (this *HistoryControlFixture) enter(cleObj *CLE, commands ...string) []string {
+	for _, command := range commands {
+		cleObj.data = []rune(command)
+		cleObj.saveHistoryEntry()
+	}
+	var history []string
+	for _, command := range cleObj.history.commands {
+		history = append(history, string(command))
+	}
+	return history
+}
+
+func (this *HistoryControlFixture) TestIgnoreDupsByDefault() {
+	cleObj := NewCLE(TestMode(true))
+	this.So(this.enter(cleObj, "make test", "make test", " git status", "make test"), should.Resemble,
+		[]string{"make test", " git status", "make test"})
+}
+
+func (this *HistoryControlFixture) TestNoPolicy() {
+	cleObj := NewCLE(TestMode(true), HistoryControl(0))
+	this.So(this.enter(cleObj, "make test", "make test"), should.Resemble, []string{"make test", "make test"})
+}
+
+func (this *HistoryControlFixture) TestIgnoreSpace() {
+	cleObj := NewCLE(TestMode(true), HistoryControl(IgnoreSpace))
+	this.So(this.enter(cleObj, " export TOKEN=abc", "git status", "git status"), should.Resemble,
+		[]string{"git status", "git status"})
+}
+
+func (this *HistoryControlFixture) TestIgnoreBoth() {
+	cleObj := NewCLE(TestMode(true), HistoryControl(IgnoreBoth))
+	this.So(this.enter(cleObj, " export TOKEN=abc", "git status", "git status"), should.Resemble, []string{"git status"})
+}
+
+func (this *HistoryControlFixture) TestEraseDups() {
+	cleObj := NewCLE(TestMode(true), HistoryControl(EraseDups))
+	cleObj.history.saved = 3
+	this.So(this.enter(cleObj, "git status", "make test", "git diff", "git status", "git status"), should.Resemble,
+		[]string{"make test", "git diff", "git status"})
+	this.So(cleObj.history.saved, should.Equal, 2)
+	this.So(len(cleObj.history.details), should.Equal, 3)
+}
+
+func (this *HistoryControlFixture) TestEraseDupsOnLoad() {
+	cleObj := NewCLE(TestMode(true), HistoryControl(EraseDups), History(NewMemoryHistory(
+		HistoryEntry{Command: "git status"}, HistoryEntry{Command: "make test"}, HistoryEntry{Command: "git status"})))
+	this.So(this.enter(cleObj), should.Resemble, []string{"make test", "git status"})
+	this.So(cleObj.history.saved, should.Equal, 2)
+}
+
+func (this *HistoryControlFixture) TestIgnorePatterns() {
+	cleObj := NewCLE(TestMode(true), IgnorePatterns([]string{"*password=*", "/--token[= ]/", "ls*", "*.[!t]?m"}))
+	this.So(this.enter(cleObj,
+		"mysql --user=root password=hunter2",
+		"curl --password=ab/cd https://example.com",
+		"cat ~/.ssh/id.pem | ssh-add -",
+		"cat ~/notes.txm",
+		"deploy --token abc123",
+		"lsblk --all",
+		"deploy --tokens",
+		"make install",
+	), should.Resemble, []string{"cat ~/notes.txm", "deploy --tokens", "make install"})
+}
+
+func (this *HistoryControlFixture) TestGlobs() {
+	for glob, expression := range map[string]string{
+		"*password=*": `(?s)^.*password=.*$`,
+		"a?c":         `(?s)^a.c$`,
+		"[!a-c]x":     `(?s)^[^a-c]x$`,
+		"[]]":         `(?s)^[\]]$`,
+		`a\*.+`:       `(?s)^a\*\.\+$`,
+	} {
+		translated, err := globExpression(glob)
+		this.So(err, should.BeNil)
+		this.So(translated, should.Equal, expression)
+	}
+}
+
+func (this *HistoryControlFixture) TestInvalidPatternsAreLeftOut() {
+	cleObj := NewCLE(TestMode(true), IgnorePatterns([]string{"[", "*secret*", "/(/", "[z-a]"}))
+	this.So(len(cleObj.historyIgnores), should.Equal, 1)
+
+	_, err := cleObj.ReadLine("> ")
+	this.So(err, should.NotBeNil)
+	this.So(err.Error(), should.Equal, `cle: IgnorePatterns("["): syntax error in pattern`)
+	this.So(errors.Is(err, path.ErrBadPattern), should.BeTrue)
+}
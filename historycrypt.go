@@ -0,0 +1,112 @@
+package cle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ENCRYPTED_HISTORY_HEADER begins an encrypted history file, followed by the
+// nonce and the history sealed with AES-GCM.
+const ENCRYPTED_HISTORY_HEADER = "CLE ENCRYPTED HISTORY 1\n"
+
+// ErrHistoryDecryption is the error, wrapped, of a history file that cannot
+// be decrypted: the key is wrong, the file was tampered with, or the file is
+// not encrypted when it should be or the other way round (see
+// MigratePlainHistory). The file is left as it is, and LoadHistory and
+// SaveHistoryErr return the error, or else the next read (see ReadLine).
+var ErrHistoryDecryption = errors.New("cle: history file cannot be decrypted")
+
+// NewEncryptedFileHistory returns a HistoryStore that keeps the history in the
+// named file, like NewFileHistory, encrypted with the key (AES-GCM with a
+// 16, 24 or 32 byte key, for AES-128, AES-192 or AES-256).
+func NewEncryptedFileHistory(name string, size int, format HistoryFileFormat, key []byte) (HistoryStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &fileHistory{name: name, size: size, format: format, aead: aead}, nil
+}
+
+// migratePlainHistory has the encrypted store read a history file that is not
+// encrypted, which is encrypted when next written (see MigratePlainHistory).
+func (this *fileHistory) migratePlainHistory() {
+	this.migrate = true
+}
+
+// seal encrypts the history, if the store has a key.
+func (this *fileHistory) seal(history []byte) ([]byte, error) {
+	if this.aead == nil {
+		return history, nil
+	}
+	nonce := make([]byte, this.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append([]byte(ENCRYPTED_HISTORY_HEADER), nonce...)
+	return this.aead.Seal(sealed, nonce, history, []byte(ENCRYPTED_HISTORY_HEADER)), nil
+}
+
+// open decrypts the contents of the history file, if the store has a key.
+func (this *fileHistory) open(contents []byte) ([]byte, error) {
+	encrypted := bytes.HasPrefix(contents, []byte(ENCRYPTED_HISTORY_HEADER))
+	if this.aead == nil {
+		if encrypted {
+			return nil, fmt.Errorf("%w: %s is encrypted and no key is configured", ErrHistoryDecryption, this.name)
+		}
+		return contents, nil
+	}
+	if len(contents) == 0 {
+		return nil, nil
+	}
+	if !encrypted && this.migrate {
+		return contents, nil
+	}
+	if !encrypted {
+		return nil, fmt.Errorf("%w: %s is not encrypted", ErrHistoryDecryption, this.name)
+	}
+
+	sealed := contents[len(ENCRYPTED_HISTORY_HEADER):]
+	if len(sealed) < this.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %s is truncated", ErrHistoryDecryption, this.name)
+	}
+	nonce, ciphertext := sealed[:this.aead.NonceSize()], sealed[this.aead.NonceSize():]
+	history, err := this.aead.Open(nil, nonce, ciphertext, []byte(ENCRYPTED_HISTORY_HEADER))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: wrong key, or the file was tampered with", ErrHistoryDecryption, this.name)
+	}
+	return history, nil
+}
+
+// newHistoryFileStore returns the store of the history file, encrypted if
+// there is a key (see HistoryEncryptionKey). A store with an invalid key
+// fails every use with the error of the key.
+func (this *CLE) newHistoryFileStore() HistoryStore {
+	if this.historyKey == nil {
+		return NewFileHistory(this.historyFile, this.historyMax, this.historyFormat)
+	}
+	store, err := NewEncryptedFileHistory(this.historyFile, this.historyMax, this.historyFormat, this.historyKey)
+	if err != nil {
+		return unavailableHistory{err: fmt.Errorf("cle: history encryption key: %w", err)}
+	}
+	if this.migrateHistory {
+		store.(*fileHistory).migratePlainHistory()
+	}
+	return store
+}
+
+// unavailableHistory is a HistoryStore that cannot be used.
+type unavailableHistory struct{ err error }
+
+func (this unavailableHistory) Load() ([]HistoryEntry, error)         { return nil, this.err }
+func (this unavailableHistory) Append(...HistoryEntry) error          { return this.err }
+func (this unavailableHistory) Search(string) ([]HistoryEntry, error) { return nil, this.err }
+func (this unavailableHistory) Clear() error                          { return this.err }
+func (this unavailableHistory) Close() error                          { return nil }
@@ -0,0 +1,161 @@
+package cle
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestHistoryCryptFixture(t *testing.T) {
+	gunit.Run(new(HistoryCryptFixture), t)
+}
+
+type HistoryCryptFixture struct {
+	*gunit.Fixture
+	historyFiles
+	key []byte
+}
+
+func (this *HistoryCryptFixture) Setup() {
+	this.setUp(this.Fixture)
+	this.key = bytes.Repeat([]byte{7}, 32)
+}
+
+func (this *HistoryCryptFixture) Teardown() {
+	this.tearDown()
+}
+
+func (this *HistoryCryptFixture) save(cleObj *CLE, command string) {
+	this.enter(cleObj, command)
+	cleObj.SaveHistory()
+}
+
+func (this *HistoryCryptFixture) TestRoundTrip() {
+	this.save(this.newCLE(HistoryEncryptionKey(this.key)), "export SECRET=value")
+
+	contents, _ := os.ReadFile(this.file)
+	this.So(string(contents), should.StartWith, ENCRYPTED_HISTORY_HEADER)
+	this.So(string(contents), should.NotContainSubstring, "SECRET")
+
+	reader := this.newCLE(HistoryEncryptionKey(this.key))
+	this.So(reader.history.commands, should.Resemble, [][]byte{[]byte("export SECRET=value")})
+	this.save(reader, "second command")
+	this.So(len(this.newCLE(HistoryEncryptionKey(this.key)).history.commands), should.Equal, 2)
+}
+
+func (this *HistoryCryptFixture) TestWrongKey() {
+	this.save(this.newCLE(HistoryEncryptionKey(this.key)), "export SECRET=value")
+
+	cleObj := this.newCLE(HistoryEncryptionKey(bytes.Repeat([]byte{8}, 32)))
+	this.So(cleObj.history.commands, should.BeEmpty)
+	err := cleObj.LoadHistory()
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+	this.So(err.Error(), should.ContainSubstring, "wrong key, or the file was tampered with")
+}
+
+func (this *HistoryCryptFixture) TestTampering() {
+	this.save(this.newCLE(HistoryEncryptionKey(this.key)), "export SECRET=value")
+	contents, _ := os.ReadFile(this.file)
+	contents[len(contents)-1] ^= 1
+	this.So(os.WriteFile(this.file, contents, 0600), should.BeNil)
+
+	err := this.newCLE(HistoryEncryptionKey(this.key)).LoadHistory()
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+}
+
+func (this *HistoryCryptFixture) TestFirstReadReturnsLoadError() {
+	this.So(os.WriteFile(this.file, []byte(ENCRYPTED_HISTORY_HEADER+"garbage"), 0600), should.BeNil)
+	cleObj := NewCLE(HistoryFile(this.file), HistoryEncryptionKey(this.key),
+		Input(strings.NewReader("ls\r")), Output(io.Discard), BracketedPaste(false))
+
+	_, err := cleObj.ReadLine("> ")
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+	line, err := cleObj.ReadLine("> ")
+	this.So(line, should.Equal, "ls")
+	this.So(err, should.BeNil)
+}
+
+func (this *HistoryCryptFixture) TestNextReadReturnsSaveError() {
+	cleObj := NewCLE(HistoryFile(this.file), HistoryEncryptionKey(this.key), HistoryAppend(true),
+		Input(strings.NewReader("make test\rmake lint\r")), Output(io.Discard), BracketedPaste(false))
+	this.So(os.WriteFile(this.file, []byte(ENCRYPTED_HISTORY_HEADER+"garbage"), 0600), should.BeNil)
+
+	line, err := cleObj.ReadLine("> ")
+	this.So(line, should.Equal, "make test")
+	this.So(err, should.BeNil)
+	_, err = cleObj.ReadLine("> ")
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+
+	cleObj.SaveHistory()
+	_, err = cleObj.ReadLine("> ")
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+	this.So(cleObj.history.commands, should.Resemble, [][]byte{[]byte("make test")})
+}
+
+func (this *HistoryCryptFixture) TestSaveOverTamperedFileFails() {
+	cleObj := this.newCLE(HistoryEncryptionKey(this.key))
+	this.So(os.WriteFile(this.file, []byte(ENCRYPTED_HISTORY_HEADER+"garbage"), 0600), should.BeNil)
+
+	this.enter(cleObj, "another command")
+	err := cleObj.SaveHistoryErr()
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+	this.So(cleObj.history.commands, should.Resemble, [][]byte{[]byte("another command")})
+	contents, _ := os.ReadFile(this.file)
+	this.So(string(contents), should.Equal, ENCRYPTED_HISTORY_HEADER+"garbage")
+}
+
+func (this *HistoryCryptFixture) TestPlainFileWithKeyIsLeftAlone() {
+	this.So(os.WriteFile(this.file, []byte("plain command\n"), 0600), should.BeNil)
+
+	cleObj := this.newCLE(HistoryEncryptionKey(this.key))
+	this.So(errors.Is(cleObj.LoadHistory(), ErrHistoryDecryption), should.BeTrue)
+	this.enter(cleObj, "export SECRET=value")
+	this.So(errors.Is(cleObj.SaveHistoryErr(), ErrHistoryDecryption), should.BeTrue)
+	contents, _ := os.ReadFile(this.file)
+	this.So(string(contents), should.Equal, "plain command\n")
+}
+
+func (this *HistoryCryptFixture) TestPlainFileIsMigrated() {
+	this.So(os.WriteFile(this.file, []byte("plain command\n"), 0600), should.BeNil)
+
+	cleObj := this.newCLE(HistoryEncryptionKey(this.key), MigratePlainHistory(true))
+	this.So(cleObj.LoadHistory(), should.BeNil)
+	this.So(cleObj.history.commands, should.Resemble, [][]byte{[]byte("plain command")})
+	this.save(cleObj, "export SECRET=value")
+
+	contents, _ := os.ReadFile(this.file)
+	this.So(string(contents), should.StartWith, ENCRYPTED_HISTORY_HEADER)
+	this.So(string(contents), should.NotContainSubstring, "plain command")
+	reader := this.newCLE(HistoryEncryptionKey(this.key))
+	this.So(reader.LoadHistory(), should.BeNil)
+	this.So(reader.history.commands, should.Resemble, [][]byte{[]byte("plain command"), []byte("export SECRET=value")})
+}
+
+func (this *HistoryCryptFixture) TestEncryptedFileWithoutKey() {
+	this.save(this.newCLE(HistoryEncryptionKey(this.key)), "export SECRET=value")
+
+	err := this.newCLE().LoadHistory()
+	this.So(errors.Is(err, ErrHistoryDecryption), should.BeTrue)
+}
+
+func (this *HistoryCryptFixture) TestEmptyFile() {
+	this.So(os.WriteFile(this.file, nil, 0600), should.BeNil)
+	this.So(this.newCLE(HistoryEncryptionKey(this.key)).history.commands, should.BeEmpty)
+}
+
+func (this *HistoryCryptFixture) TestInvalidKey() {
+	for _, key := range [][]byte{[]byte("short"), nil} {
+		cleObj := this.newCLE(HistoryEncryptionKey(key))
+		this.So(cleObj.LoadHistory(), should.NotBeNil)
+		this.enter(cleObj, "export SECRET=value")
+		this.So(cleObj.SaveHistoryErr(), should.NotBeNil)
+	}
+	_, err := os.Stat(this.file)
+	this.So(os.IsNotExist(err), should.BeTrue)
+}
@@ -0,0 +1,154 @@
+package cle
+
+import (
+	"crypto/cipher"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileHistory is the HistoryStore of a history file (see NewFileHistory).
+type fileHistory struct {
+	lock    sync.Mutex // serializes the changes of the sessions in this process
+	name    string
+	size    int
+	format  HistoryFileFormat
+	aead    cipher.AEAD // encrypts the file, if there is a key (see NewEncryptedFileHistory)
+	migrate bool        // reads a file not yet encrypted (see MigratePlainHistory)
+}
+
+// NewFileHistory returns a HistoryStore that keeps the history in the named
+// file, in the format given, keeping at most size of the newest entries.
+// (HistoryFile uses it.) The file may be shared by every session using it:
+// each change to the file is made holding an advisory lock on the file named
+// with a ".lock" suffix, and replaces the file in one step, so that sessions
+// saving at once cannot lose each other's entries and a crash cannot leave
+// the file half written.
+func NewFileHistory(name string, size int, format HistoryFileFormat) HistoryStore {
+	return &fileHistory{name: name, size: size, format: format}
+}
+
+func (this *fileHistory) Load() ([]HistoryEntry, error) {
+	contents, err := os.ReadFile(this.name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	history, err := this.open(contents)
+	if err != nil {
+		return nil, err
+	}
+	return parseHistory(history)
+}
+
+// Append adds the entries to those saved in the file (by this session or any
+// other), keeping the newest.
+func (this *fileHistory) Append(entries ...HistoryEntry) error {
+	unlock, err := this.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	saved, err := this.Load()
+	if err != nil {
+		return err
+	}
+	entries = append(saved, entries...)
+	if startIndex := len(entries) - this.size; startIndex > 0 {
+		entries = entries[startIndex:]
+	}
+	history, err := this.seal(formatHistory(entries, this.format))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(this.name, history, 0644)
+}
+
+func (this *fileHistory) Search(query string) ([]HistoryEntry, error) {
+	entries, err := this.Load()
+	return searchHistory(entries, query), err
+}
+
+func (this *fileHistory) Clear() error {
+	unlock, err := this.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err = os.Remove(this.name); os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (this *fileHistory) Close() error {
+	return nil
+}
+
+// lockFile waits for an exclusive advisory lock on the history file's lock
+// file (the history file itself is replaced when saved, so cannot hold the
+// lock), and returns the function to release it.
+func (this *fileHistory) lockFile() (unlock func(), err error) {
+	this.lock.Lock()
+	file, err := os.OpenFile(this.name+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err == nil {
+		err = flock(file, unix.LOCK_EX)
+		if err != nil {
+			_ = file.Close()
+		}
+	}
+	if err != nil {
+		this.lock.Unlock()
+		return nil, err
+	}
+	return func() {
+		_ = flock(file, unix.LOCK_UN)
+		_ = file.Close()
+		this.lock.Unlock()
+	}, nil
+}
+
+////////////////////////////////////////////
+
+// writeFileAtomically writes data to a temporary file beside the named file,
+// which it then replaces, so that the named file has either its old contents
+// or the new, whatever happens.
+func writeFileAtomically(name string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }() // fails harmlessly once renamed
+
+	if info, statErr := os.Stat(name); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+func flock(file *os.File, how int) error {
+	for {
+		err := unix.Flock(int(file.Fd()), how)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package cle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestHistoryFileFixture(t *testing.T) {
+	gunit.Run(new(HistoryFileFixture), t)
+}
+
+type HistoryFileFixture struct {
+	*gunit.Fixture
+	historyFiles
+}
+
+func (this *HistoryFileFixture) Setup() {
+	this.setUp(this.Fixture)
+}
+
+func (this *HistoryFileFixture) Teardown() {
+	this.tearDown()
+}
+
+func (this *HistoryFileFixture) fileContents() string {
+	contents, err := os.ReadFile(this.file)
+	this.So(err, should.BeNil)
+	return string(contents)
+}
+
+func (this *HistoryFileFixture) TestSessionsSavingMergeTheirCommands() {
+	this.So(os.WriteFile(this.file, []byte("command zero\n"), 0600), should.BeNil)
+	first, second := this.newCLE(), this.newCLE()
+	this.enter(first, "command one")
+	this.enter(second, "command two")
+
+	first.SaveHistory()
+	second.SaveHistory()
+	this.So(this.fileContents(), should.Equal, "command zero\ncommand one\ncommand two\n")
+	this.So(len(second.history.commands), should.Equal, 3)
+
+	first.SaveHistory() // nothing new to save
+	this.So(this.fileContents(), should.Equal, "command zero\ncommand one\ncommand two\n")
+}
+
+func (this *HistoryFileFixture) TestSaveKeepsTheNewestCommands() {
+	this.So(os.WriteFile(this.file, []byte("command zero\ncommand one\n"), 0600), should.BeNil)
+	cleObj := this.newCLE(HistorySize(3))
+	this.enter(cleObj, "command two", "command three")
+
+	cleObj.SaveHistory()
+	this.So(this.fileContents(), should.Equal, "command one\ncommand two\ncommand three\n")
+}
+
+func (this *HistoryFileFixture) TestSaveReplacesTheFile() {
+	this.So(os.WriteFile(this.file, nil, 0600), should.BeNil)
+	cleObj := this.newCLE()
+	this.enter(cleObj, "command one")
+
+	cleObj.SaveHistory()
+	info, err := os.Stat(this.file)
+	this.So(err, should.BeNil)
+	this.So(info.Mode().Perm(), should.Equal, os.FileMode(0600))
+	entries, _ := os.ReadDir(this.directory)
+	this.So(len(entries), should.Equal, 2) // the history file and its lock file, and no temporary file
+}
+
+func (this *HistoryFileFixture) TestHistoryAppend() {
+	cleObj := this.newCLE(HistoryAppend(true))
+	this.enter(cleObj, "command one")
+	this.So(this.fileContents(), should.Equal, "command one\n")
+
+	other := this.newCLE()
+	this.enter(other, "command two")
+	other.SaveHistory()
+	this.enter(cleObj, "command three")
+	this.So(this.fileContents(), should.Equal, "command one\ncommand two\ncommand three\n")
+
+	cleObj.SaveHistory()
+	this.So(this.fileContents(), should.Equal, "command one\ncommand two\ncommand three\n")
+}
+
+func (this *HistoryFileFixture) TestLoadKeepsTheNewestCommands() {
+	var history strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&history, "command number %d\n", i)
+	}
+	this.So(os.WriteFile(this.file, []byte(history.String()), 0600), should.BeNil)
+
+	cleObj := this.newCLE()
+	this.So(len(cleObj.history.commands), should.Equal, HISTORY_MAX_DEFAULT)
+	this.So(string(cleObj.history.commands[0]), should.Equal, "command number 900")
+	this.So(string(cleObj.history.commands[99]), should.Equal, "command number 999")
+}
+
+func (this *HistoryFileFixture) TestSearch() {
+	this.So(os.WriteFile(this.file, []byte("git status\nmake test\ngit commit\n"), 0600), should.BeNil)
+
+	entries, err := NewFileHistory(this.file, 100, PlainHistory).Search("Git")
+	this.So(err, should.BeNil)
+	this.So(entries, should.Resemble, []HistoryEntry{{Command: "git status"}, {Command: "git commit"}})
+}
+
+func (this *HistoryFileFixture) TestClearWithoutFile() {
+	this.So(NewFileHistory(this.file, 100, PlainHistory).Clear(), should.BeNil)
+}
+
+////////////////////////////////////////////
+
+// historyFiles keeps the history file of a fixture in a temporary directory,
+// for the fixtures of the history files, which embed it alongside
+// *gunit.Fixture.
+type historyFiles struct {
+	directory string
+	file      string
+}
+
+func (this *historyFiles) setUp(fixture *gunit.Fixture) {
+	var err error
+	this.directory, err = os.MkdirTemp("", "cle-history-test-*")
+	fixture.So(err, should.BeNil)
+	this.file = filepath.Join(this.directory, "history")
+}
+
+func (this *historyFiles) tearDown() {
+	_ = os.RemoveAll(this.directory)
+}
+
+// newCLE returns a CLE keeping its history in the history file.
+func (this *historyFiles) newCLE(options ...Option) *CLE {
+	return NewCLE(append([]Option{TestMode(true), HistoryFile(this.file)}, options...)...)
+}
+
+// enter adds the commands to the history as if entered.
+func (this *historyFiles) enter(cleObj *CLE, commands ...string) {
+	for _, command := range commands {
+		cleObj.data = []rune(command)
+		cleObj.saveHistoryEntry()
+	}
+}
@@ -0,0 +1,128 @@
+package cle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// HistoryFileFormat is the format of the history file (see HistoryFormat).
+// Files in either format are read, whatever the format written.
+type HistoryFileFormat int
+
+const (
+	// PlainHistory is a command per line, and nothing more. A command that
+	// would not read back as it was (one with a line break, or that begins
+	// with {) is written as a line of ExtendedHistory, without the details.
+	PlainHistory HistoryFileFormat = iota
+
+	// ExtendedHistory is a JSON object per line, recording with each command
+	// when and where it was entered and its exit status (see
+	// RecordExitStatus), and the session it was entered in, e.g.
+	//	{"time":1700000000,"directory":"/home/user","exit_status":0,"session":"5f2c9a1e0d3b7c64","command":"make test"}
+	// A command can contain line breaks in this format.
+	ExtendedHistory
+)
+
+// historyDetails records when and where a command of the history was entered
+// (see HistoryEntry).
+type historyDetails struct {
+	time       time.Time
+	directory  string
+	exitStatus *int
+	session    string
+}
+
+// extendedHistoryLine is a line of an ExtendedHistory file.
+type extendedHistoryLine struct {
+	Time       int64   `json:"time,omitempty"`
+	Directory  string  `json:"directory,omitempty"`
+	ExitStatus *int    `json:"exit_status,omitempty"`
+	Session    string  `json:"session,omitempty"`
+	Command    *string `json:"command"`
+}
+
+// RecordExitStatus records the exit status of the command last entered, to
+// be saved with it (e.g. in an ExtendedHistory file), unless the command has
+// been saved already (see HistoryAppend).
+func (this *CLE) RecordExitStatus(status int) {
+	if last := len(this.history.commands) - 1; last >= this.history.saved && last < len(this.history.details) {
+		this.history.details[last].exitStatus = &status
+	}
+}
+
+// newHistoryDetails returns the details of a command entered now.
+func (this *CLE) newHistoryDetails() historyDetails {
+	directory, _ := os.Getwd()
+	return historyDetails{time: time.Now(), directory: directory, session: this.session}
+}
+
+////////////////////////////////////////////
+
+// formatHistory returns the entries in the format of a history file.
+func formatHistory(entries []HistoryEntry, format HistoryFileFormat) (history []byte) {
+	for _, entry := range entries {
+		if format == ExtendedHistory {
+			history = append(history, formatExtendedHistoryLine(entry)...)
+		} else if strings.ContainsAny(entry.Command, "\r\n") || strings.HasPrefix(entry.Command, "{") {
+			history = append(history, formatExtendedHistoryLine(HistoryEntry{Command: entry.Command})...)
+		} else {
+			history = append(history, entry.Command...)
+		}
+		history = append(history, '\n')
+	}
+	return history
+}
+
+// parseHistory returns the entries of a history file in either format, line
+// by line: a line that is not a JSON object with a command is a command of a
+// PlainHistory file.
+func parseHistory(history []byte) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(bytes.NewReader(history))
+	scanner.Buffer(nil, len(history)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var extended extendedHistoryLine
+		if bytes.HasPrefix(line, []byte("{")) && json.Unmarshal(line, &extended) == nil && extended.Command != nil {
+			entries = append(entries, parseExtendedHistoryLine(extended))
+		} else {
+			entries = append(entries, HistoryEntry{Command: string(line)})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func parseExtendedHistoryLine(line extendedHistoryLine) HistoryEntry {
+	entry := HistoryEntry{Command: *line.Command, Directory: line.Directory, ExitStatus: line.ExitStatus, Session: line.Session}
+	if line.Time != 0 {
+		entry.Time = time.Unix(line.Time, 0)
+	}
+	return entry
+}
+
+func formatExtendedHistoryLine(entry HistoryEntry) []byte {
+	line := extendedHistoryLine{
+		Directory:  entry.Directory,
+		ExitStatus: entry.ExitStatus,
+		Session:    entry.Session,
+		Command:    &entry.Command,
+	}
+	if !entry.Time.IsZero() {
+		line.Time = entry.Time.Unix()
+	}
+	encoded, _ := json.Marshal(line) // cannot fail: no type of the line fails to encode
+	return encoded
+}
+
+// newSessionID returns a random identifier for the session of a CLE.
+func newSessionID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,100 @@
+package cle
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestHistoryFormatFixture(t *testing.T) {
+	gunit.Run(new(HistoryFormatFixture), t)
+}
+
+type HistoryFormatFixture struct {
+	*gunit.Fixture
+	historyFiles
+}
+
+func (this *HistoryFormatFixture) Setup() {
+	this.setUp(this.Fixture)
+}
+
+func (this *HistoryFormatFixture) Teardown() {
+	this.tearDown()
+}
+
+func (this *HistoryFormatFixture) TestParseEitherFormat() {
+	entries, err := parseHistory([]byte("plain command\n" +
+		`{"time":1700000000,"directory":"/tmp","exit_status":2,"session":"abc","command":"make\ntest"}` + "\n" +
+		`{"not":"an entry"}` + "\n" +
+		"{broken\n"))
+
+	this.So(err, should.BeNil)
+	this.So(len(entries), should.Equal, 4)
+	this.So(entries[0], should.Resemble, HistoryEntry{Command: "plain command"})
+	this.So(entries[1].Command, should.Equal, "make\ntest")
+	this.So(entries[1].Time, should.Equal, time.Unix(1700000000, 0))
+	this.So(entries[1].Directory, should.Equal, "/tmp")
+	this.So(*entries[1].ExitStatus, should.Equal, 2)
+	this.So(entries[1].Session, should.Equal, "abc")
+	this.So(entries[2].Command, should.Equal, `{"not":"an entry"}`)
+	this.So(entries[3].Command, should.Equal, "{broken")
+}
+
+func (this *HistoryFormatFixture) TestFormatExtendedHistory() {
+	status := 0
+	history := formatHistory([]HistoryEntry{
+		{Command: "legacy"},
+		{Command: "ls", Time: time.Unix(1700000000, 0), Directory: "/tmp", ExitStatus: &status, Session: "abc"},
+	}, ExtendedHistory)
+	this.So(string(history), should.Equal, `{"command":"legacy"}`+"\n"+
+		`{"time":1700000000,"directory":"/tmp","exit_status":0,"session":"abc","command":"ls"}`+"\n")
+}
+
+func (this *HistoryFormatFixture) TestExtendedHistoryRoundTrip() {
+	this.So(os.WriteFile(this.file, []byte("legacy command\n"), 0600), should.BeNil)
+	writer := this.newCLE(HistoryFormat(ExtendedHistory))
+	this.enter(writer, "select *\nfrom table")
+	writer.RecordExitStatus(3)
+	writer.SaveHistory()
+
+	contents, _ := os.ReadFile(this.file)
+	this.So(strings.Count(string(contents), "\n"), should.Equal, 2)
+
+	reader := this.newCLE()
+	this.So(reader.history.commands, should.Resemble, [][]byte{[]byte("legacy command"), []byte("select *\nfrom table")})
+	details := reader.history.details[1]
+	this.So(time.Since(details.time), should.BeLessThan, time.Minute)
+	this.So(details.directory, should.NotBeBlank)
+	this.So(*details.exitStatus, should.Equal, 3)
+	this.So(details.session, should.Equal, writer.session)
+	this.So(reader.session, should.NotEqual, writer.session)
+
+	this.enter(reader, "plain again")
+	reader.SaveHistory() // rewritten plain, dropping the details
+	contents, _ = os.ReadFile(this.file)
+	this.So(string(contents), should.Equal, "legacy command\n"+`{"command":"select *\nfrom table"}`+"\nplain again\n")
+}
+
+func (this *HistoryFormatFixture) TestPlainHistoryKeepsCommandsWhole() {
+	commands := []HistoryEntry{{Command: "select *\nfrom table"}, {Command: `{"command":"ls"}`}, {Command: "ls", Session: "abc"}}
+	history := formatHistory(commands, PlainHistory)
+	this.So(string(history), should.Equal,
+		`{"command":"select *\nfrom table"}`+"\n"+`{"command":"{\"command\":\"ls\"}"}`+"\nls\n")
+
+	entries, err := parseHistory(history)
+	this.So(err, should.BeNil)
+	this.So(entries, should.Resemble, []HistoryEntry{{Command: "select *\nfrom table"}, {Command: `{"command":"ls"}`}, {Command: "ls"}})
+}
+
+func (this *HistoryFormatFixture) TestRecordExitStatusOnlyForUnsavedCommands() {
+	cleObj := this.newCLE(HistoryAppend(true))
+	cleObj.RecordExitStatus(1) // no command yet
+	this.enter(cleObj, "appended command")
+	cleObj.RecordExitStatus(1)
+	this.So(cleObj.history.details[0].exitStatus, should.BeNil)
+}
@@ -0,0 +1,204 @@
+package cle
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryStore keeps the command history between sessions, e.g. in a file
+// (see NewFileHistory and HistoryFile) or a database. A store may be shared
+// by several sessions at once.
+type HistoryStore interface {
+	// Load returns the entries of the history, oldest first.
+	Load() ([]HistoryEntry, error)
+
+	// Append adds the entries to the end of the history.
+	Append(entries ...HistoryEntry) error
+
+	// Search returns the entries of the history whose commands contain the
+	// query, ignoring case, oldest first.
+	Search(query string) ([]HistoryEntry, error)
+
+	// Clear removes every entry from the history.
+	Clear() error
+
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// HistoryEntry is a command of the history, with when and where it was
+// entered as far as known.
+type HistoryEntry struct {
+	Command    string
+	Time       time.Time // zero when unknown
+	Directory  string    // the working directory
+	ExitStatus *int      // nil when unknown (see RecordExitStatus)
+	Session    string    // identifies the CLE the command was entered in
+}
+
+// SaveHistory adds the commands entered since the history was loaded or last
+// saved to the history store, and loads the newest commands of the store (see
+// HistorySize), merged with those saved by other sessions in the meantime, as
+// bash does with histappend. Any error is returned by the next read (see
+// ReadLine), or by SaveHistoryErr instead.
+func (this *CLE) SaveHistory() {
+	this.fail(this.SaveHistoryErr())
+}
+
+// SaveHistoryErr saves the history, like SaveHistory, and returns the error,
+// if any, of the history store. Commands that cannot be saved are kept, to be
+// saved by the next call.
+func (this *CLE) SaveHistoryErr() error {
+	if this.historyStore == nil {
+		this.trimHistory()
+		return nil
+	}
+	if err := this.appendHistory(); err != nil {
+		return err
+	}
+	return this.reloadHistory()
+}
+
+// LoadHistory loads the history from the history store again, in place of the
+// commands in memory (those not yet saved are lost), and returns the error, if
+// any, of the store, leaving the commands in memory as they are. NewCLE loads
+// the history too, and the first read returns any error of that load (e.g.
+// ErrHistoryDecryption, see ReadLine); call LoadHistory to have it before
+// reading.
+func (this *CLE) LoadHistory() error {
+	if this.historyStore == nil {
+		return nil
+	}
+	return this.reloadHistory()
+}
+
+// appendHistory adds the commands not yet saved to the history store.
+func (this *CLE) appendHistory() error {
+	entries := this.history.entries(this.history.saved)
+	if len(entries) > 0 {
+		if err := this.historyStore.Append(entries...); err != nil {
+			return err
+		}
+	}
+	this.history.saved = len(this.history.commands)
+	return nil
+}
+
+// reloadHistory loads the history from the history store in place of the
+// commands in memory, which are left as they are if it cannot be loaded.
+func (this *CLE) reloadHistory() error {
+	entries, err := this.historyStore.Load()
+	if err != nil {
+		return err
+	}
+	this.history.commands, this.history.details = this.history.commands[:0], this.history.details[:0]
+	this.addHistoryEntries(entries)
+	this.tidyHistory()
+	return nil
+}
+
+func (this *CLE) loadHistoryStore() error {
+	entries, err := this.historyStore.Load()
+	this.addHistoryEntries(entries)
+	return err
+}
+
+func (this *CLE) addHistoryEntries(entries []HistoryEntry) {
+	for _, entry := range entries {
+		this.history.add([]byte(entry.Command), historyDetails{
+			time:       entry.Time,
+			directory:  entry.Directory,
+			exitStatus: entry.ExitStatus,
+			session:    entry.Session,
+		})
+	}
+}
+
+func (this *CommandHistory) add(command []byte, details historyDetails) {
+	for len(this.details) < len(this.commands) {
+		this.details = append(this.details, historyDetails{})
+	}
+	this.details = append(this.details[:len(this.commands)], details)
+	this.commands = append(this.commands, command)
+}
+
+// entries returns the commands from the first given, with their details.
+func (this *CommandHistory) entries(first int) []HistoryEntry {
+	var entries []HistoryEntry
+	for i := first; i < len(this.commands); i++ {
+		entry := HistoryEntry{Command: string(this.commands[i])}
+		if i < len(this.details) {
+			details := this.details[i]
+			entry.Time, entry.Directory, entry.ExitStatus, entry.Session = details.time, details.directory, details.exitStatus, details.session
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// trim keeps the newest commands, at most size of them.
+func (this *CommandHistory) trim(size int) {
+	startIndex := len(this.commands) - size
+	if startIndex <= 0 {
+		return
+	}
+	this.saved = clamp(this.saved-startIndex, 0, size)
+	this.commands = this.commands[startIndex:]
+	this.details = this.details[min(startIndex, len(this.details)):]
+}
+
+// memoryHistory is a HistoryStore in memory (see NewMemoryHistory).
+type memoryHistory struct {
+	lock    sync.Mutex
+	entries []HistoryEntry
+}
+
+// NewMemoryHistory returns a HistoryStore that keeps the history in memory,
+// e.g. for tests or sessions sharing a history within a process.
+func NewMemoryHistory(entries ...HistoryEntry) HistoryStore {
+	return &memoryHistory{entries: entries}
+}
+
+func (this *memoryHistory) Load() ([]HistoryEntry, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return append([]HistoryEntry(nil), this.entries...), nil
+}
+
+func (this *memoryHistory) Append(entries ...HistoryEntry) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.entries = append(this.entries, entries...)
+	return nil
+}
+
+func (this *memoryHistory) Search(query string) ([]HistoryEntry, error) {
+	entries, _ := this.Load()
+	return searchHistory(entries, query), nil
+}
+
+func (this *memoryHistory) Clear() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.entries = nil
+	return nil
+}
+
+func (this *memoryHistory) Close() error {
+	return nil
+}
+
+////////////////////////////////////////////
+
+// searchHistory returns the entries whose commands contain the query,
+// ignoring case.
+func searchHistory(entries []HistoryEntry, query string) (found []HistoryEntry) {
+	query = strings.ToLower(query)
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Command), query) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
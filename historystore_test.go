@@ -0,0 +1,119 @@
+package cle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestHistoryStoreFixture(t *testing.T) {
+	gunit.Run(new(HistoryStoreFixture), t)
+}
+
+type HistoryStoreFixture struct {
+	*gunit.Fixture
+	store HistoryStore
+}
+
+func (this *HistoryStoreFixture) Setup() {
+	this.store = NewMemoryHistory(HistoryEntry{Command: "git status"}, HistoryEntry{Command: "make test"})
+}
+
+func (this *HistoryStoreFixture) TestHistoryIsLoadedFromTheStore() {
+	cleObj := NewCLE(TestMode(true), History(this.store))
+	this.So(cleObj.history.commands, should.Resemble, [][]byte{[]byte("git status"), []byte("make test")})
+	this.So(cleObj.history.currentPosition, should.Equal, 2)
+}
+
+func (this *HistoryStoreFixture) TestSaveHistoryAppendsNewCommands() {
+	first, second := NewCLE(TestMode(true), History(this.store)), NewCLE(TestMode(true), History(this.store))
+	first.data = []rune("go vet ./...")
+	first.saveHistoryEntry()
+	second.data = []rune("go test ./...")
+	second.saveHistoryEntry()
+
+	first.SaveHistory()
+	second.SaveHistory()
+	entries, _ := this.store.Load()
+	this.So(len(entries), should.Equal, 4)
+	this.So(entries[2].Command, should.Equal, "go vet ./...")
+	this.So(entries[2].Session, should.Equal, first.session)
+	this.So(entries[3].Command, should.Equal, "go test ./...")
+	this.So(len(second.history.commands), should.Equal, 4)
+
+	first.SaveHistory() // nothing new
+	entries, _ = this.store.Load()
+	this.So(len(entries), should.Equal, 4)
+}
+
+func (this *HistoryStoreFixture) TestHistoryAppend() {
+	cleObj := NewCLE(TestMode(true), History(this.store), HistoryAppend(true))
+	cleObj.data = []rune("go vet ./...")
+	cleObj.saveHistoryEntry()
+
+	entries, _ := this.store.Load()
+	this.So(len(entries), should.Equal, 3)
+}
+
+func (this *HistoryStoreFixture) TestFailedSaveIsRetried() {
+	failing := &failingHistory{HistoryStore: this.store, err: errors.New("unavailable")}
+	cleObj := NewCLE(TestMode(true), History(failing))
+	cleObj.data = []rune("go vet ./...")
+	cleObj.saveHistoryEntry()
+
+	cleObj.SaveHistory()
+	failing.err = nil
+	cleObj.SaveHistory()
+	entries, _ := this.store.Load()
+	this.So(len(entries), should.Equal, 3)
+}
+
+func (this *HistoryStoreFixture) TestFailedLoadKeepsHistory() {
+	failing := &failingHistory{HistoryStore: this.store}
+	cleObj := NewCLE(TestMode(true), History(failing))
+	cleObj.data = []rune("go vet ./...")
+	cleObj.saveHistoryEntry()
+
+	failing.loadErr = errors.New("unavailable")
+	this.So(cleObj.SaveHistoryErr(), should.Equal, failing.loadErr)
+	this.So(cleObj.LoadHistory(), should.Equal, failing.loadErr)
+	this.So(cleObj.history.commands, should.Resemble,
+		[][]byte{[]byte("git status"), []byte("make test"), []byte("go vet ./...")})
+	this.So(cleObj.history.saved, should.Equal, 3)
+}
+
+func (this *HistoryStoreFixture) TestClearHistoryClearsTheStore() {
+	cleObj := NewCLE(TestMode(true), History(this.store))
+	cleObj.ClearHistory()
+
+	entries, _ := this.store.Load()
+	this.So(entries, should.BeEmpty)
+}
+
+func (this *HistoryStoreFixture) TestSearch() {
+	entries, err := this.store.Search("STATUS")
+	this.So(err, should.BeNil)
+	this.So(entries, should.Resemble, []HistoryEntry{{Command: "git status"}})
+}
+
+type failingHistory struct {
+	HistoryStore
+	err     error // of Append
+	loadErr error // of Load
+}
+
+func (this *failingHistory) Load() ([]HistoryEntry, error) {
+	if this.loadErr != nil {
+		return nil, this.loadErr
+	}
+	return this.HistoryStore.Load()
+}
+
+func (this *failingHistory) Append(entries ...HistoryEntry) error {
+	if this.err != nil {
+		return this.err
+	}
+	return this.HistoryStore.Append(entries...)
+}
@@ -0,0 +1,174 @@
+package cle
+
+import "unicode"
+
+// incrementalSearch is the readline-style history search started with CTL-R
+// (ReverseSearchHistory, towards older entries) or CTL-S (ForwardSearchHistory,
+// towards newer entries). The input shows
+// the matching history entry while the query is typed.
+type incrementalSearch struct {
+	active   bool
+	forward  bool
+	failed   bool
+	query    []rune
+	match    int    // the index of the matching history entry
+	position int    // the offset of the query within the match
+	data     []rune // the input from before the search, restored on cancel
+	cursor   int
+}
+
+func (this *CLE) reverseSearchHistory() error {
+	this.startIncrementalSearch(false)
+	this.repaint()
+	return nil
+}
+
+func (this *CLE) forwardSearchHistory() error {
+	this.startIncrementalSearch(true)
+	this.repaint()
+	return nil
+}
+
+// handleIncrementalSearch consumes the keys that edit the query or move
+// between matches while the search is active. Escape or CTL-G cancels the
+// search; any other key accepts the match and is then processed as usual.
+func (this *CLE) handleIncrementalSearch(key Key) bool {
+	if !this.search.active {
+		return false
+	}
+
+	switch action := this.keyMap[key]; {
+	case action == ReverseSearchHistory:
+		this.nextIncrementalMatch(false)
+	case action == ForwardSearchHistory:
+		this.nextIncrementalMatch(true)
+	case key == Key{Code: ESCAPE_KEY} || key == Ctrl('g'):
+		this.cancelIncrementalSearch()
+	case action == BackwardDeleteChar:
+		if len(this.search.query) > 0 {
+			this.search.query = this.search.query[:len(this.search.query)-1]
+			this.findIncrementalMatch(len(this.history.commands)-1, false)
+		}
+	case action == "" && key.isCharacter():
+		this.extendIncrementalSearch([]rune{key.Code})
+	default:
+		this.acceptIncrementalSearch()
+		return false
+	}
+	this.repaint()
+	return true
+}
+
+func (this *CLE) startIncrementalSearch(forward bool) {
+	this.clearSearchMode()
+	this.search = incrementalSearch{
+		active:  true,
+		forward: forward,
+		match:   len(this.history.commands),
+		data:    append([]rune(nil), this.data...),
+		cursor:  this.cursorPosition,
+	}
+}
+
+func (this *CLE) extendIncrementalSearch(runes []rune) {
+	for _, r := range runes {
+		if isInsertableRune(r) {
+			this.search.query = append(this.search.query, r)
+		}
+	}
+	from := this.search.match
+	if from >= len(this.history.commands) {
+		from = len(this.history.commands) - 1
+	}
+	this.findIncrementalMatch(from, this.search.forward)
+}
+
+func (this *CLE) nextIncrementalMatch(forward bool) {
+	this.search.forward = forward
+	if len(this.search.query) == 0 {
+		return
+	}
+	if forward {
+		this.findIncrementalMatch(this.search.match+1, true)
+	} else {
+		this.findIncrementalMatch(this.search.match-1, false)
+	}
+}
+
+// findIncrementalMatch finds the first history entry containing the query,
+// starting at from and moving in the given direction. The previous match is
+// kept, and the search marked as failed, when there is none.
+func (this *CLE) findIncrementalMatch(from int, forward bool) {
+	step := -1
+	if forward {
+		step = 1
+	}
+	query := lowerRunes(this.search.query)
+	for i := from; i >= 0 && i < len(this.history.commands); i += step {
+		position := indexRunes(lowerRunes([]rune(string(this.history.commands[i]))), query)
+		if position < 0 {
+			continue
+		}
+		this.search.failed = false
+		this.search.match = i
+		this.search.position = position
+		this.data = append(this.data[:0], []rune(string(this.history.commands[i]))...)
+		this.cursorPosition = position
+		return
+	}
+	this.search.failed = len(query) > 0
+}
+
+func (this *CLE) acceptIncrementalSearch() {
+	if this.search.match < len(this.history.commands) {
+		this.history.currentPosition = this.search.match
+	}
+	this.search = incrementalSearch{}
+}
+
+func (this *CLE) cancelIncrementalSearch() {
+	this.data = append(this.data[:0], this.search.data...)
+	this.cursorPosition = this.search.cursor
+	this.search = incrementalSearch{}
+}
+
+// incrementalSearchPrompt replaces the prompt during the search, e.g. (reverse-i-search)'query':
+func (this *CLE) incrementalSearchPrompt() string {
+	prompt := "(reverse-i-search)'"
+	if this.search.forward {
+		prompt = "(i-search)'"
+	}
+	if this.search.failed {
+		prompt = "(failed " + prompt[1:]
+	}
+	return prompt + string(this.search.query) + "': "
+}
+
+// incrementalSearchHighlight returns the span of the input matching the query.
+func (this *CLE) incrementalSearchHighlight() (start, end int) {
+	if len(this.search.query) == 0 || this.search.match >= len(this.history.commands) {
+		return 0, 0
+	}
+	start = clamp(this.search.position, 0, len(this.data))
+	end = clamp(start+len(this.search.query), start, len(this.data))
+	return start, end
+}
+
+////////////////////////////////////////////
+
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func indexRunes(runes, sub []rune) int {
+	for i := 0; i+len(sub) <= len(runes); i++ {
+		if string(runes[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
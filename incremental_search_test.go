@@ -0,0 +1,111 @@
+package cle
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestIncrementalSearchFixture(t *testing.T) {
+	gunit.Run(new(IncrementalSearchFixture), t)
+}
+
+type IncrementalSearchFixture struct {
+	*gunit.Fixture
+	cle *CLE
+}
+
+func (this *IncrementalSearchFixture) Setup() {
+	this.cle = NewCLE(TestMode(true))
+	this.cle.history.commands = [][]byte{
+		[]byte("git status"),
+		[]byte("make test"),
+		[]byte("git commit -m 'Status'"),
+		[]byte("ls -la"),
+	}
+	this.cle.history.currentPosition = len(this.cle.history.commands)
+	this.cle.data = []rune("typed")
+	this.cle.cursorPosition = 5
+}
+
+func (this *IncrementalSearchFixture) key(keys ...byte) (err error) {
+	for _, key := range keys {
+		err = press(this.cle, key)
+	}
+	return err
+}
+
+func (this *IncrementalSearchFixture) TestInactiveSearchIgnoresOtherKeys() {
+	this.So(this.cle.handleIncrementalSearch(Key{Code: 'a'}), should.BeFalse)
+	this.So(this.cle.search.active, should.BeFalse)
+}
+
+func (this *IncrementalSearchFixture) TestSearchUpdatesOnEachKeystroke() {
+	this.key(CONTROL_R)
+	this.So(this.cle.search.active, should.BeTrue)
+	this.So(this.cle.incrementalSearchPrompt(), should.Equal, "(reverse-i-search)'': ")
+
+	this.key('s')
+	this.So(string(this.cle.data), should.Equal, "ls -la")
+	this.key('t')
+	this.So(string(this.cle.data), should.Equal, "git commit -m 'Status'")
+	this.So(this.cle.cursorPosition, should.Equal, 15)
+	start, end := this.cle.incrementalSearchHighlight()
+	this.So(string(this.cle.data[start:end]), should.Equal, "St")
+	this.key('a')
+	this.So(string(this.cle.data), should.Equal, "git commit -m 'Status'")
+	this.So(this.cle.incrementalSearchPrompt(), should.Equal, "(reverse-i-search)'sta': ")
+}
+
+func (this *IncrementalSearchFixture) TestRepeatedSearchMovesThroughMatches() {
+	this.key(CONTROL_R)
+	this.key('s', 't', 'a')
+	this.So(string(this.cle.data), should.Equal, "git commit -m 'Status'")
+
+	this.key(CONTROL_R)
+	this.So(string(this.cle.data), should.Equal, "git status")
+
+	this.key(CONTROL_R) // no older match
+	this.So(string(this.cle.data), should.Equal, "git status")
+	this.So(this.cle.incrementalSearchPrompt(), should.Equal, "(failed reverse-i-search)'sta': ")
+
+	this.key(CONTROL_S)
+	this.So(string(this.cle.data), should.Equal, "git commit -m 'Status'")
+	this.So(this.cle.incrementalSearchPrompt(), should.Equal, "(i-search)'sta': ")
+}
+
+func (this *IncrementalSearchFixture) TestDeleteShortensQuery() {
+	this.key(CONTROL_R)
+	this.key('m', 'a', 'x')
+	this.So(this.cle.search.failed, should.BeTrue)
+
+	this.key(DELETE_KEY)
+	this.So(this.cle.search.failed, should.BeFalse)
+	this.So(string(this.cle.data), should.Equal, "make test")
+}
+
+func (this *IncrementalSearchFixture) TestEscapeCancelsSearch() {
+	this.key(CONTROL_R)
+	this.key('m', 'a')
+	this.So(this.cle.handleIncrementalSearch(Key{Code: ESCAPE_KEY}), should.BeTrue)
+
+	this.So(this.cle.search.active, should.BeFalse)
+	this.So(string(this.cle.data), should.Equal, "typed")
+	this.So(this.cle.cursorPosition, should.Equal, 5)
+}
+
+func (this *IncrementalSearchFixture) TestOtherKeysAcceptMatch() {
+	this.key(CONTROL_R)
+	this.key('m', 'a')
+
+	this.So(this.cle.handleIncrementalSearch(Key{Code: KeyLeft}), should.BeFalse)
+	this.So(this.cle.search.active, should.BeFalse)
+	this.So(string(this.cle.data), should.Equal, "make test")
+	this.So(this.cle.history.currentPosition, should.Equal, 1)
+
+	this.key(CONTROL_R, 'm', 'a')
+	this.So(this.key(ENTER_KEY), should.Equal, errLineAccepted)
+	this.So(string(this.cle.data), should.Equal, "make test")
+	this.So(string(this.cle.history.commands[len(this.cle.history.commands)-1]), should.Equal, "make test")
+}
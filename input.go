@@ -0,0 +1,111 @@
+package cle
+
+import (
+	"io"
+	"time"
+)
+
+// inputSource is the input of a read. The read waits at most timeout for
+// input (indefinitely when the timeout is negative) and returns no input when
+// the timeout expires, so that the CLE can tell a lone ESC from the start of
+// an escape sequence (see EscapeTimeout).
+type inputSource interface {
+	read(timeout time.Duration) ([]byte, error)
+
+	// interrupt ends the read in progress (or else the next read) early,
+	// with no input.
+	interrupt()
+}
+
+// inputPump is the inputSource for a configured input (see Input). As an
+// io.Reader cannot time out, it is read in the background for the life of
+// the CLE (until Close), at most one read ahead of the input handled.
+type inputPump struct {
+	chunks chan inputChunk
+	wake   chan struct{}
+	done   chan struct{} // closed by stop
+	err    error         // the error ending the input, once received
+}
+
+type inputChunk struct {
+	data []byte
+	err  error
+}
+
+func newInputPump() *inputPump {
+	return &inputPump{chunks: make(chan inputChunk), wake: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+func startInputPump(reader io.Reader) *inputPump {
+	pump := newInputPump()
+	go pump.run(reader)
+	return pump
+}
+
+// run reads the input until it ends or the pump is stopped. A read already
+// blocked in the reader still has to return before run can.
+func (this *inputPump) run(reader io.Reader) {
+	for {
+		buffer := make([]byte, 256)
+		numRead, err := reader.Read(buffer)
+		if numRead > 0 && !this.send(inputChunk{data: buffer[:numRead]}) {
+			return
+		}
+		if err != nil {
+			this.send(inputChunk{err: err})
+			return
+		}
+	}
+}
+
+// send hands the chunk to a read, reporting false if the pump is stopped
+// first.
+func (this *inputPump) send(chunk inputChunk) bool {
+	select {
+	case this.chunks <- chunk:
+		return true
+	case <-this.done:
+		return false
+	}
+}
+
+func (this *inputPump) read(timeout time.Duration) ([]byte, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case chunk := <-this.chunks:
+		this.err = chunk.err
+		return chunk.data, chunk.err
+	case <-expired:
+		return nil, nil
+	case <-this.wake:
+		return nil, nil
+	case <-this.done:
+		return nil, ErrClosed
+	}
+}
+
+func (this *inputPump) interrupt() {
+	select {
+	case this.wake <- struct{}{}:
+	default: // already interrupted
+	}
+}
+
+// stop ends the pump, and any read from it. The caller serializes calls.
+func (this *inputPump) stop() {
+	select {
+	case <-this.done: // already stopped
+	default:
+		close(this.done)
+	}
+}
@@ -0,0 +1,82 @@
+package cle
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestInputFixture(t *testing.T) {
+	gunit.Run(new(InputFixture), t)
+}
+
+type InputFixture struct {
+	*gunit.Fixture
+}
+
+func (this *InputFixture) TestInputPumpDeliversInput() {
+	pump := startInputPump(strings.NewReader("abc"))
+
+	input, err := pump.read(-1)
+	this.So(err, should.BeNil)
+	this.So(string(input), should.Equal, "abc")
+
+	input, err = pump.read(-1)
+	this.So(err, should.Equal, io.EOF)
+	this.So(input, should.BeNil)
+
+	_, err = pump.read(-1)
+	this.So(err, should.Equal, io.EOF)
+}
+
+func (this *InputFixture) TestInputPumpTimesOut() {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	pump := startInputPump(reader)
+
+	input, err := pump.read(time.Millisecond)
+	this.So(err, should.BeNil)
+	this.So(input, should.BeNil)
+
+	go writer.Write([]byte("a"))
+	input, err = pump.read(time.Second)
+	this.So(err, should.BeNil)
+	this.So(string(input), should.Equal, "a")
+}
+
+func (this *InputFixture) TestStoppedInputPumpEnds() {
+	pump := newInputPump()
+	ended := make(chan struct{})
+	go func() {
+		pump.run(strings.NewReader("abc"))
+		close(ended)
+	}()
+
+	pump.stop()
+	pump.stop()
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		this.Error("the pump is still running")
+	}
+	_, err := pump.read(-1)
+	this.So(err, should.Equal, ErrClosed)
+}
+
+func (this *InputFixture) TestCloseStopsInputPump() {
+	cleObj := NewCLE(Input(strings.NewReader("abc\rdef")), Output(io.Discard))
+	line, err := cleObj.ReadLine("> ")
+	this.So(string(line), should.Equal, "abc")
+	this.So(err, should.BeNil)
+
+	this.So(cleObj.Close(), should.BeNil)
+	select {
+	case <-cleObj.pump.done:
+	default:
+		this.Error("the pump was not stopped")
+	}
+}
@@ -0,0 +1,201 @@
+package cle
+
+import "errors"
+
+// Action names an editor command that a key can be bound to. The names
+// follow GNU readline where there is an equivalent.
+type Action string
+
+const (
+	AcceptLine           Action = "accept-line"
+	BeginningOfLine      Action = "beginning-of-line"
+	EndOfLine            Action = "end-of-line"
+	BackwardChar         Action = "backward-char"
+	ForwardChar          Action = "forward-char"
+	BackwardWord         Action = "backward-word"
+	ForwardWord          Action = "forward-word"
+	PreviousHistory      Action = "previous-history"
+	NextHistory          Action = "next-history"
+	BackwardDeleteChar   Action = "backward-delete-char"
+	DeleteChar           Action = "delete-char" // end of input on an empty line
+	DeleteForwardChar    Action = "delete-forward-char"
+	KillLine             Action = "kill-line"
+	UnixLineDiscard      Action = "unix-line-discard"
+	KillWholeLine        Action = "kill-whole-line"
+	UnixWordRubout       Action = "unix-word-rubout"
+	KillWord             Action = "kill-word"
+	TransposeChars       Action = "transpose-chars"
+	Complete             Action = "complete"
+	ReverseSearchHistory Action = "reverse-search-history"
+	ForwardSearchHistory Action = "forward-search-history"
+	ClearScreen          Action = "clear-screen"
+	Yank                 Action = "yank"
+	YankPop              Action = "yank-pop"
+	Interrupt            Action = "interrupt" // the read ends with ErrInterrupted
+	Suspend              Action = "suspend"
+	Undo                 Action = "undo"
+	Redo                 Action = "redo"
+)
+
+// KeyMap binds keys to actions. Keys bound to no action insert their
+// character, if they have one, and are otherwise ignored.
+type KeyMap map[Key]Action
+
+// Binding binds a key to an action; an empty action removes the key's binding.
+type Binding struct {
+	Key    Key
+	Action Action
+}
+
+// Bind returns the binding of key to action.
+func Bind(key Key, action Action) Binding {
+	return Binding{Key: key, Action: action}
+}
+
+// LegacyKeyMap returns the original CLE key bindings (see README.md), where
+// e.g. CTL-B deletes to the beginning of the line and CTL-N deletes the line.
+// This is the default key map.
+func LegacyKeyMap() KeyMap {
+	return KeyMap{
+		Ctrl('a'):                            BeginningOfLine,
+		Ctrl('b'):                            UnixLineDiscard,
+		Ctrl('d'):                            DeleteChar,
+		Ctrl('e'):                            EndOfLine,
+		Ctrl('i'):                            Complete,
+		Ctrl('k'):                            KillLine,
+		Ctrl('m'):                            AcceptLine,
+		Ctrl('n'):                            KillWholeLine,
+		Ctrl('r'):                            ReverseSearchHistory,
+		Ctrl('s'):                            ForwardSearchHistory,
+		Ctrl('w'):                            UnixWordRubout,
+		Ctrl('y'):                            Yank,
+		Ctrl('_'):                            Undo,
+		Ctrl('c'):                            Interrupt,
+		Ctrl('z'):                            Suspend,
+		{Code: DELETE_KEY}:                   BackwardDeleteChar,
+		{Code: KeyUp}:                        PreviousHistory,
+		{Code: KeyDown}:                      NextHistory,
+		{Code: KeyLeft}:                      BackwardChar,
+		{Code: KeyRight}:                     ForwardChar,
+		{Code: KeyLeft, Modifiers: ModAlt}:   BackwardWord,
+		{Code: KeyRight, Modifiers: ModAlt}:  ForwardWord,
+		{Code: KeyLeft, Modifiers: ModCtrl}:  BackwardWord,
+		{Code: KeyRight, Modifiers: ModCtrl}: ForwardWord,
+		{Code: KeyHome}:                      BeginningOfLine,
+		{Code: KeyEnd}:                       EndOfLine,
+		{Code: KeyDelete}:                    DeleteForwardChar,
+		{Code: '∂'}:                          KillWord, // Option-D in the macOS Terminal
+		Alt('b'):                             BackwardWord,
+		Alt('f'):                             ForwardWord,
+		Alt('d'):                             KillWord,
+		Alt(DELETE_KEY):                      UnixWordRubout,
+		Alt('y'):                             YankPop,
+		Alt('_'):                             Redo,
+	}
+}
+
+// EmacsKeyMap returns key bindings compatible with the GNU readline emacs
+// mode, e.g. CTL-B moves back a character and CTL-N recalls the next command.
+func EmacsKeyMap() KeyMap {
+	keyMap := LegacyKeyMap()
+	emacs := KeyMap{
+		Ctrl('b'): BackwardChar,
+		Ctrl('f'): ForwardChar,
+		Ctrl('h'): BackwardDeleteChar,
+		Ctrl('j'): AcceptLine,
+		Ctrl('l'): ClearScreen,
+		Ctrl('n'): NextHistory,
+		Ctrl('p'): PreviousHistory,
+		Ctrl('t'): TransposeChars,
+		Ctrl('u'): UnixLineDiscard,
+	}
+	for key, action := range emacs {
+		keyMap[key] = action
+	}
+	return keyMap
+}
+
+// errLineAccepted ends a read with the current input as the line.
+var errLineAccepted = errors.New("cle: line accepted")
+
+var actions = map[Action]func(*CLE) error{
+	AcceptLine:           (*CLE).acceptLine,
+	BeginningOfLine:      (*CLE).beginningOfLine,
+	EndOfLine:            (*CLE).endOfLine,
+	BackwardChar:         (*CLE).backwardChar,
+	ForwardChar:          (*CLE).forwardChar,
+	BackwardWord:         (*CLE).backwardWord,
+	ForwardWord:          (*CLE).forwardWord,
+	PreviousHistory:      (*CLE).previousHistory,
+	NextHistory:          (*CLE).nextHistory,
+	BackwardDeleteChar:   (*CLE).backwardDeleteChar,
+	DeleteChar:           (*CLE).deleteChar,
+	DeleteForwardChar:    (*CLE).deleteForwardChar,
+	KillLine:             (*CLE).killLine,
+	UnixLineDiscard:      (*CLE).unixLineDiscard,
+	KillWholeLine:        (*CLE).killWholeLine,
+	UnixWordRubout:       (*CLE).unixWordRubout,
+	KillWord:             (*CLE).killWord,
+	TransposeChars:       (*CLE).transposeChars,
+	Complete:             (*CLE).complete,
+	ReverseSearchHistory: (*CLE).reverseSearchHistory,
+	ForwardSearchHistory: (*CLE).forwardSearchHistory,
+	ClearScreen:          (*CLE).clearScreen,
+	Yank:                 (*CLE).yank,
+	YankPop:              (*CLE).yankPop,
+	Interrupt:            (*CLE).interrupt,
+	Suspend:              (*CLE).suspend,
+	Undo:                 (*CLE).undo,
+	Redo:                 (*CLE).redo,
+}
+
+// handleKey performs the action bound to key, or inserts the key's character
+// when it is unbound, recording any edit so it can be undone. The error is
+// errLineAccepted when the line is complete.
+func (this *CLE) handleKey(key Key) error {
+	before := this.snapshot()
+	err := this.dispatchKey(key)
+	this.recordEdit(before, key)
+	return err
+}
+
+func (this *CLE) dispatchKey(key Key) error {
+	action, bound := this.keyMap[key]
+	this.kills.follow(action)
+
+	if key.Code == keyPaste {
+		this.completion = completion{}
+		this.paste(this.decoder.pasted())
+		return nil
+	}
+
+	if this.password && !passwordAction(action) {
+		return nil
+	}
+	if this.handleIncrementalSearch(key) {
+		return nil
+	}
+	if this.viMode {
+		if handled, err := this.handleViKey(key); handled {
+			return err
+		}
+	}
+
+	if action != Complete {
+		this.completion = completion{}
+	}
+	if bound {
+		return this.perform(action)
+	}
+	if key.isCharacter() {
+		this.insertRunes([]rune{key.Code})
+	}
+	return nil
+}
+
+func (this *CLE) perform(action Action) error {
+	if perform, found := actions[action]; found {
+		return perform(this)
+	}
+	return nil
+}
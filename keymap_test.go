@@ -0,0 +1,101 @@
+package cle
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestKeyMapFixture(t *testing.T) {
+	gunit.Run(new(KeyMapFixture), t)
+}
+
+type KeyMapFixture struct {
+	*gunit.Fixture
+}
+
+func (this *KeyMapFixture) TestKeyConstructors() {
+	this.So(Ctrl('a'), should.Resemble, Key{Code: CONTROL_A})
+	this.So(Ctrl('W'), should.Resemble, Key{Code: CONTROL_W})
+	this.So(Ctrl('['), should.Resemble, Key{Code: ESCAPE_KEY})
+	this.So(Alt('b'), should.Resemble, Key{Code: 'b', Modifiers: ModAlt})
+}
+
+func (this *KeyMapFixture) TestEmacsKeyMap() {
+	cleObj := NewCLE(TestMode(true), KeyMapping(EmacsKeyMap()))
+	cleObj.history.commands = [][]byte{[]byte("first command"), []byte("second command")}
+	cleObj.history.currentPosition = 2
+	cleObj.data = []rune("test command")
+	cleObj.cursorPosition = 5
+
+	cleObj.handleKey(Ctrl('b'))
+	this.So(cleObj.cursorPosition, should.Equal, 4)
+	this.So(string(cleObj.data), should.Equal, "test command")
+
+	cleObj.handleKey(Ctrl('f'))
+	this.So(cleObj.cursorPosition, should.Equal, 5)
+
+	cleObj.handleKey(Ctrl('u'))
+	this.So(string(cleObj.data), should.Equal, "command")
+
+	cleObj.handleKey(Ctrl('p'))
+	this.So(string(cleObj.data), should.Equal, "second command")
+	cleObj.handleKey(Ctrl('p'))
+	this.So(string(cleObj.data), should.Equal, "first command")
+	cleObj.handleKey(Ctrl('n'))
+	this.So(string(cleObj.data), should.Equal, "second command")
+}
+
+func (this *KeyMapFixture) TestLegacyKeyMapIsDefault() {
+	cleObj := NewCLE(TestMode(true))
+	this.So(cleObj.keyMap, should.Resemble, LegacyKeyMap())
+}
+
+func (this *KeyMapFixture) TestBindingsOverrideKeyMap() {
+	base := EmacsKeyMap()
+	cleObj := NewCLE(TestMode(true),
+		Bindings(Bind(Ctrl('t'), EndOfLine), Bind(Ctrl('k'), "")),
+		KeyMapping(base),
+	)
+	this.So(cleObj.keyMap[Ctrl('t')], should.Equal, EndOfLine)
+	this.So(base[Ctrl('t')], should.Equal, TransposeChars) // the option copies the key map
+
+	cleObj.data = []rune("test command")
+	cleObj.cursorPosition = 0
+	cleObj.handleKey(Ctrl('t'))
+	this.So(cleObj.cursorPosition, should.Equal, 12)
+
+	cleObj.cursorPosition = 4
+	cleObj.handleKey(Ctrl('k')) // unbound
+	this.So(string(cleObj.data), should.Equal, "test command")
+}
+
+func (this *KeyMapFixture) TestBoundCharacterPerformsAction() {
+	cleObj := NewCLE(TestMode(true), Bindings(Bind(Key{Code: '~'}, BeginningOfLine)))
+	cleObj.data = []rune("test")
+	cleObj.cursorPosition = 4
+
+	cleObj.handleKey(Key{Code: '~'})
+	this.So(string(cleObj.data), should.Equal, "test")
+	this.So(cleObj.cursorPosition, should.BeZeroValue)
+}
+
+func (this *KeyMapFixture) TestTransposeChars() {
+	cleObj := NewCLE(TestMode(true))
+
+	cleObj.data = []rune("abcd")
+	cleObj.cursorPosition = 1
+	cleObj.transposeChars()
+	this.So(string(cleObj.data), should.Equal, "bacd")
+	this.So(cleObj.cursorPosition, should.Equal, 2)
+
+	cleObj.cursorPosition = 4 // at the end, the two characters before the cursor
+	cleObj.transposeChars()
+	this.So(string(cleObj.data), should.Equal, "badc")
+	this.So(cleObj.cursorPosition, should.Equal, 4)
+
+	cleObj.cursorPosition = 0
+	cleObj.transposeChars()
+	this.So(string(cleObj.data), should.Equal, "badc")
+}
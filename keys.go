@@ -0,0 +1,257 @@
+package cle
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Key is a keystroke: a character, a control character (e.g. CONTROL_A) or
+// one of the special keys below, possibly pressed with modifier keys.
+type Key struct {
+	Code      rune
+	Modifiers Modifier
+}
+
+// Modifier is a set of modifier keys held down with a Key.
+type Modifier uint8
+
+const (
+	ModAlt Modifier = 1 << iota
+	ModCtrl
+	ModShift
+)
+
+// Special keys have negative codes so they cannot be mistaken for characters.
+const (
+	KeyUp rune = -1 - iota
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyInsert
+	KeyDelete
+	KeyPageUp
+	KeyPageDown
+
+	keyPaste // text pasted in bracketed paste mode, taken from the decoder with pasted
+)
+
+// Ctrl returns the key for the control character produced by holding CTL
+// with r, e.g. Ctrl('a') is CONTROL_A.
+func Ctrl(r rune) Key {
+	if r >= 'a' && r <= 'z' {
+		r -= 'a' - 'A'
+	}
+	return Key{Code: r & 0x1F}
+}
+
+// Alt returns the key for r pressed with Alt (sent by most terminals as ESC followed by r).
+func Alt(r rune) Key {
+	return Key{Code: r, Modifiers: ModAlt}
+}
+
+// isCharacter reports whether the key inserts a character into the input.
+func (this Key) isCharacter() bool {
+	return this.Modifiers == 0 && isInsertableRune(this.Code)
+}
+
+// keyDecoder turns the bytes read from the terminal into keys, whether the
+// bytes of a key arrive in separate reads or several keys arrive in one read.
+// It recognizes UTF-8 characters, control characters, CSI (ESC [) and SS3
+// (ESC O) sequences for the special keys, with xterm-style modifiers, and
+// Alt-prefixed (ESC-prefixed) keys.
+//
+// Text pasted in bracketed paste mode (between PASTE_START and PASTE_END)
+// is decoded as a whole, as the key keyPaste.
+type keyDecoder struct {
+	buffer []byte
+	paste  string
+}
+
+type decoding int
+
+const (
+	decoded    decoding = iota // a key was decoded
+	incomplete                 // more bytes are needed
+	skipped                    // the bytes are not a known key
+)
+
+func (this *keyDecoder) write(data []byte) {
+	this.buffer = append(this.buffer, data...)
+}
+
+// pending reports whether the buffer ends with an incomplete key.
+func (this *keyDecoder) pending() bool {
+	return len(this.buffer) > 0
+}
+
+// decode returns the next key in the buffer. It reports false when there is
+// no complete key, unless flush is set (when no more bytes arrive within the
+// escape timeout), in which case a pending ESC is taken as the Escape key.
+func (this *keyDecoder) decode(flush bool) (Key, bool) {
+	for len(this.buffer) > 0 {
+		if bytes.HasPrefix(this.buffer, []byte(PASTE_START)) {
+			return this.decodePaste()
+		}
+		key, size, result := decodeKey(this.buffer, flush)
+		if result == incomplete {
+			return Key{}, false
+		}
+		this.buffer = this.buffer[size:]
+		if result == decoded {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// decodePaste waits for the end of the paste, however long the wait, so that
+// no part of the pasted text is taken as keys.
+func (this *keyDecoder) decodePaste() (Key, bool) {
+	end := bytes.Index(this.buffer, []byte(PASTE_END))
+	if end < 0 {
+		return Key{}, false
+	}
+	this.paste = string(this.buffer[len(PASTE_START):end])
+	this.buffer = this.buffer[end+len(PASTE_END):]
+	return Key{Code: keyPaste}, true
+}
+
+// pasted returns the text of the last keyPaste.
+func (this *keyDecoder) pasted() string {
+	paste := this.paste
+	this.paste = ""
+	return paste
+}
+
+func decodeKey(buffer []byte, flush bool) (key Key, size int, result decoding) {
+	if buffer[0] != ESCAPE_KEY {
+		return decodeCharacter(buffer, flush)
+	}
+	if len(buffer) == 1 {
+		if flush {
+			return Key{Code: ESCAPE_KEY}, 1, decoded
+		}
+		return Key{}, 0, incomplete
+	}
+
+	switch buffer[1] {
+	case ARROW_KEY_INDICATOR:
+		key, size, result = decodeCSI(buffer)
+	case SS3_INDICATOR:
+		key, size, result = decodeSS3(buffer)
+	case ESCAPE_KEY: // ESC ESC [ D: Alt+Left on some terminals
+		key, size, result = decodeKey(buffer[1:], flush)
+		key.Modifiers |= ModAlt
+		return key, size + 1, result
+	default:
+		key, size, result = decodeCharacter(buffer[1:], flush)
+		key.Modifiers |= ModAlt
+		return key, size + 1, result
+	}
+
+	if result == incomplete && flush { // ESC [ or ESC O typed as Alt+[ or Alt+O
+		return Alt(rune(buffer[1])), 2, decoded
+	}
+	return key, size, result
+}
+
+func decodeCharacter(buffer []byte, flush bool) (Key, int, decoding) {
+	if !utf8.FullRune(buffer) {
+		if flush {
+			return Key{}, len(buffer), skipped
+		}
+		return Key{}, 0, incomplete
+	}
+	r, size := utf8.DecodeRune(buffer)
+	if r == utf8.RuneError {
+		return Key{}, size, skipped
+	}
+	return Key{Code: r}, size, decoded
+}
+
+// decodeCSI decodes ESC [ <parameters> <final byte>, e.g. ESC [ A (Up),
+// ESC [ 3 ~ (Delete) or ESC [ 1 ; 5 C (Ctrl+Right).
+func decodeCSI(buffer []byte) (Key, int, decoding) {
+	end := 2
+	for end < len(buffer) && (buffer[end] < 0x40 || buffer[end] > 0x7E) {
+		end++
+	}
+	if end == len(buffer) {
+		return Key{}, 0, incomplete
+	}
+
+	parameters := strings.Split(string(buffer[2:end]), ";")
+	modifiers := Modifier(0)
+	if len(parameters) > 1 {
+		modifiers = decodeModifiers(parameters[1])
+	}
+
+	code, known := csiKeys[buffer[end]]
+	if buffer[end] == '~' {
+		code, known = tildeKeys[parameters[0]]
+	}
+	if buffer[end] == 'Z' { // Shift+Tab
+		code, known, modifiers = TAB_KEY, true, modifiers|ModShift
+	}
+	if !known {
+		return Key{}, end + 1, skipped
+	}
+	return Key{Code: code, Modifiers: modifiers}, end + 1, decoded
+}
+
+// decodeSS3 decodes ESC O <final byte>, sent for the cursor keys in
+// application mode, e.g. ESC O A (Up).
+func decodeSS3(buffer []byte) (Key, int, decoding) {
+	if len(buffer) < 3 {
+		return Key{}, 0, incomplete
+	}
+	code, known := csiKeys[buffer[2]]
+	if !known {
+		return Key{}, 3, skipped
+	}
+	return Key{Code: code}, 3, decoded
+}
+
+// decodeModifiers decodes the xterm modifier parameter: 1 plus the sum of
+// 1 (Shift), 2 (Alt), 4 (Ctrl) and 8 (Meta, taken as Alt).
+func decodeModifiers(parameter string) (modifiers Modifier) {
+	value, err := strconv.Atoi(parameter)
+	if err != nil || value < 2 {
+		return 0
+	}
+	value--
+	if value&1 != 0 {
+		modifiers |= ModShift
+	}
+	if value&(2|8) != 0 {
+		modifiers |= ModAlt
+	}
+	if value&4 != 0 {
+		modifiers |= ModCtrl
+	}
+	return modifiers
+}
+
+var csiKeys = map[byte]rune{
+	UP_ARROW:    KeyUp,
+	DOWN_ARROW:  KeyDown,
+	RIGHT_ARROW: KeyRight,
+	LEFT_ARROW:  KeyLeft,
+	'H':         KeyHome,
+	'F':         KeyEnd,
+}
+
+var tildeKeys = map[string]rune{
+	"1": KeyHome,
+	"2": KeyInsert,
+	"3": KeyDelete,
+	"4": KeyEnd,
+	"5": KeyPageUp,
+	"6": KeyPageDown,
+	"7": KeyHome,
+	"8": KeyEnd,
+}
@@ -0,0 +1,120 @@
+package cle
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestKeysFixture(t *testing.T) {
+	gunit.Run(new(KeysFixture), t)
+}
+
+type KeysFixture struct {
+	*gunit.Fixture
+}
+
+func (this *KeysFixture) decodeAll(input string, flush bool) (keys []Key) {
+	decoder := keyDecoder{}
+	decoder.write([]byte(input))
+	for {
+		key, decoded := decoder.decode(flush)
+		if !decoded {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func (this *KeysFixture) TestDecodeKeys() {
+	for _, test := range []struct {
+		input string
+		key   Key
+	}{
+		{"a", Key{Code: 'a'}},
+		{"é", Key{Code: 'é'}},
+		{"\x0b", Ctrl('k')},
+		{"\x1bf", Alt('f')},
+		{"\x1b\x7f", Alt(DELETE_KEY)},
+		{"\x1bé", Alt('é')},
+		{"\x1b[A", Key{Code: KeyUp}},
+		{"\x1bOB", Key{Code: KeyDown}},
+		{"\x1b[H", Key{Code: KeyHome}},
+		{"\x1b[F", Key{Code: KeyEnd}},
+		{"\x1b[1~", Key{Code: KeyHome}},
+		{"\x1b[4~", Key{Code: KeyEnd}},
+		{"\x1b[7~", Key{Code: KeyHome}},
+		{"\x1b[2~", Key{Code: KeyInsert}},
+		{"\x1b[3~", Key{Code: KeyDelete}},
+		{"\x1b[5~", Key{Code: KeyPageUp}},
+		{"\x1b[6~", Key{Code: KeyPageDown}},
+		{"\x1b[1;3D", Key{Code: KeyLeft, Modifiers: ModAlt}},
+		{"\x1b[1;5C", Key{Code: KeyRight, Modifiers: ModCtrl}},
+		{"\x1b[1;2A", Key{Code: KeyUp, Modifiers: ModShift}},
+		{"\x1b[1;9C", Key{Code: KeyRight, Modifiers: ModAlt}},
+		{"\x1b[3;5~", Key{Code: KeyDelete, Modifiers: ModCtrl}},
+		{"\x1b[Z", Key{Code: TAB_KEY, Modifiers: ModShift}},
+		{"\x1b\x1b[D", Key{Code: KeyLeft, Modifiers: ModAlt}},
+	} {
+		this.So(this.decodeAll(test.input, false), should.Resemble, []Key{test.key})
+	}
+}
+
+func (this *KeysFixture) TestDecodeSeveralKeys() {
+	this.So(this.decodeAll("ab\x1b[Cc\x01", false), should.Resemble,
+		[]Key{{Code: 'a'}, {Code: 'b'}, {Code: KeyRight}, {Code: 'c'}, Ctrl('a')})
+}
+
+func (this *KeysFixture) TestDecodeSkipsUnknownSequences() {
+	this.So(this.decodeAll("a\x1b[99~\x1b[?1;2cb\x1bOxc", false), should.Resemble,
+		[]Key{{Code: 'a'}, {Code: 'b'}, {Code: 'c'}})
+	this.So(this.decodeAll("a\xffb", false), should.Resemble, []Key{{Code: 'a'}, {Code: 'b'}})
+}
+
+func (this *KeysFixture) TestDecodeWaitsForIncompleteKeys() {
+	for _, input := range []string{"\x1b", "\x1b[", "\x1b[1;5", "\x1bO", "\xc3", "\x1b\xc3"} {
+		decoder := keyDecoder{}
+		decoder.write([]byte(input))
+		_, decoded := decoder.decode(false)
+		this.So(decoded, should.BeFalse)
+		this.So(decoder.pending(), should.BeTrue)
+	}
+
+	decoder := keyDecoder{}
+	decoder.write([]byte("\x1b[1;"))
+	_, decoded := decoder.decode(false)
+	this.So(decoded, should.BeFalse)
+	decoder.write([]byte("5D"))
+	key, decoded := decoder.decode(false)
+	this.So(decoded, should.BeTrue)
+	this.So(key, should.Resemble, Key{Code: KeyLeft, Modifiers: ModCtrl})
+	this.So(decoder.pending(), should.BeFalse)
+}
+
+func (this *KeysFixture) TestDecodeFlushesIncompleteKeys() {
+	this.So(this.decodeAll("\x1b", true), should.Resemble, []Key{{Code: ESCAPE_KEY}})
+	this.So(this.decodeAll("\x1b[", true), should.Resemble, []Key{Alt('[')})
+	this.So(this.decodeAll("\x1bO", true), should.Resemble, []Key{Alt('O')})
+	this.So(this.decodeAll("a\xc3", true), should.Resemble, []Key{{Code: 'a'}})
+}
+
+func (this *KeysFixture) TestDecodeBracketedPaste() {
+	decoder := keyDecoder{}
+	decoder.write([]byte("a\x1b[200~one\rtwo\x1b[A"))
+	key, _ := decoder.decode(false)
+	this.So(key, should.Resemble, Key{Code: 'a'})
+
+	_, decoded := decoder.decode(true) // the paste is not over, however long it takes
+	this.So(decoded, should.BeFalse)
+
+	decoder.write([]byte("\x1b[201~b"))
+	key, decoded = decoder.decode(false)
+	this.So(decoded, should.BeTrue)
+	this.So(key, should.Resemble, Key{Code: keyPaste})
+	this.So(decoder.pasted(), should.Equal, "one\rtwo\x1b[A")
+	this.So(decoder.pasted(), should.BeEmpty)
+
+	key, _ = decoder.decode(false)
+	this.So(key, should.Resemble, Key{Code: 'b'})
+}
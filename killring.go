@@ -0,0 +1,93 @@
+package cle
+
+// killRing holds the text most recently killed (deleted by the kill actions,
+// e.g. KillLine), newest last, for yanking back into the input. It is kept
+// from one line to the next.
+type killRing struct {
+	entries [][]rune
+	killed  bool // the last key killed text, so that a further kill adds to it
+	yanked  bool // the last key yanked text, so that yank-pop can replace it
+	index   int  // the entry last yanked
+	start   int  // the position of the text last yanked
+	end     int
+}
+
+var killActions = map[Action]bool{
+	KillLine:        true,
+	UnixLineDiscard: true,
+	KillWholeLine:   true,
+	UnixWordRubout:  true,
+	KillWord:        true,
+}
+
+// follow prepares the kill ring for the action of the next key: only a kill
+// following a kill adds to the last entry, and only a yank-pop following a
+// yank replaces the text yanked.
+func (this *killRing) follow(action Action) {
+	if !killActions[action] {
+		this.killed = false
+	}
+	if action != Yank && action != YankPop {
+		this.yanked = false
+	}
+}
+
+func (this *killRing) save(text []rune, backward bool) {
+	if this.killed && len(this.entries) > 0 {
+		last := len(this.entries) - 1
+		if backward {
+			this.entries[last] = append(text, this.entries[last]...)
+		} else {
+			this.entries[last] = append(this.entries[last], text...)
+		}
+	} else {
+		this.entries = append(this.entries, text)
+		if len(this.entries) > KILL_RING_SIZE {
+			this.entries = this.entries[1:]
+		}
+	}
+	this.killed = true
+}
+
+// kill deletes the input from start to end, saving it in the kill ring.
+func (this *CLE) kill(start, end int) {
+	if start >= end {
+		return
+	}
+	text := append([]rune(nil), this.data[start:end]...)
+	this.kills.save(text, end <= this.cursorPosition)
+	this.data = append(this.data[:start], this.data[end:]...)
+	this.cursorPosition = start
+}
+
+// yank inserts the text most recently killed at the cursor.
+func (this *CLE) yank() error {
+	if len(this.kills.entries) == 0 {
+		return nil
+	}
+	this.yankEntry(len(this.kills.entries) - 1)
+	this.repaint()
+	return nil
+}
+
+// yankPop replaces the text just yanked with the entry killed before it,
+// rotating through the kill ring.
+func (this *CLE) yankPop() error {
+	if !this.kills.yanked {
+		return nil
+	}
+	this.data = append(this.data[:this.kills.start], this.data[this.kills.end:]...)
+	this.cursorPosition = this.kills.start
+	this.yankEntry((this.kills.index - 1 + len(this.kills.entries)) % len(this.kills.entries))
+	this.repaint()
+	return nil
+}
+
+func (this *CLE) yankEntry(index int) {
+	this.kills.index, this.kills.start = index, this.cursorPosition
+	for _, r := range this.kills.entries[index] {
+		this.data = insert(this.data, this.cursorPosition, r)
+		this.cursorPosition++
+	}
+	this.kills.end, this.kills.yanked = this.cursorPosition, true
+}
@@ -0,0 +1,96 @@
+package cle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestKillRingFixture(t *testing.T) {
+	gunit.Run(new(KillRingFixture), t)
+}
+
+type KillRingFixture struct {
+	*gunit.Fixture
+	*lineEditing
+}
+
+func (this *KillRingFixture) Setup() {
+	this.lineEditing = newLineEditing(this.Fixture, NewCLE(TestMode(true)))
+}
+
+func (this *KillRingFixture) TestYankKilledText() {
+	this.setLine("one two three", 4)
+	this.typeKeys(Ctrl('k'))
+	this.assertLine("one ", 4)
+
+	this.typeKeys(Ctrl('a'), Ctrl('y'))
+	this.assertLine("two threeone ", 9)
+}
+
+func (this *KillRingFixture) TestConsecutiveKillsAddToOneEntry() {
+	this.setLine("one two three", 13)
+	this.typeKeys(Ctrl('w'), Ctrl('w'))
+	this.assertLine("one ", 4)
+	this.typeKeys(Alt('d')) // nothing to kill
+	this.typeKeys(Ctrl('b'))
+	this.assertLine("", 0)
+	this.So(this.cle.kills.entries, should.Resemble, [][]rune{[]rune("one two three")})
+
+	this.setLine("one two three", 0)
+	this.typeKeys(Ctrl('a'), Alt('d'), Alt('d'))
+	this.So(this.cle.kills.entries[1], should.Resemble, []rune("one two"))
+}
+
+func (this *KillRingFixture) TestKillsSeparatedByOtherKeysAreSeparateEntries() {
+	this.setLine("one two three", 13)
+	this.typeKeys(Ctrl('w'), Key{Code: KeyLeft}, Ctrl('w'))
+	this.So(this.cle.kills.entries, should.Resemble, [][]rune{[]rune("three"), []rune("two")})
+}
+
+func (this *KillRingFixture) TestYankPopRotatesThroughKills() {
+	this.setLine("one two three", 13)
+	this.typeKeys(Ctrl('w'), Key{Code: KeyLeft}, Ctrl('w'), Key{Code: KeyLeft}, Ctrl('w'))
+	this.assertLine("  ", 0)
+
+	this.typeKeys(Ctrl('y'))
+	this.assertLine("one  ", 3)
+	this.typeKeys(Alt('y'))
+	this.assertLine("two  ", 3)
+	this.typeKeys(Alt('y'))
+	this.assertLine("three  ", 5)
+	this.typeKeys(Alt('y'))
+	this.assertLine("one  ", 3)
+}
+
+func (this *KillRingFixture) TestYankPopOnlyAfterYank() {
+	this.setLine("one", 3)
+	this.typeKeys(Ctrl('w'), Alt('y'))
+	this.assertLine("", 0)
+
+	this.typeKeys(Ctrl('y'), Key{Code: 'x'}, Alt('y'))
+	this.assertLine("onex", 4)
+}
+
+func (this *KillRingFixture) TestKillRingIsBounded() {
+	for i := 0; i < KILL_RING_SIZE+2; i++ {
+		this.setLine(strings.Repeat("x", i+1), 0)
+		this.typeKeys(Ctrl('k'), Key{Code: KeyLeft})
+	}
+	this.So(len(this.cle.kills.entries), should.Equal, KILL_RING_SIZE)
+	this.So(string(this.cle.kills.entries[0]), should.Equal, "xxx")
+}
+
+func (this *KillRingFixture) TestKillRingKeptBetweenLines() {
+	this.cle = NewCLE(TestMode(true), Input(strings.NewReader("hello\x0e\rx\x19\r")), TerminalDevice(&FakeTerminal{}))
+
+	line, err := this.cle.ReadLine("> ")
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "")
+
+	line, err = this.cle.ReadLine("> ")
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "xhello")
+}
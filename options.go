@@ -1,5 +1,10 @@
 package cle
 
+import (
+	"io"
+	"time"
+)
+
 // Option is a func type received by CLE.
 // Each one allows configuration of the CLE.
 type Option func(*CLE)
@@ -16,6 +21,67 @@ func HistoryEntryMinimumLength(historyEntryMinLen int) Option {
 	return func(c *CLE) { c.historyEntryMinimumLength = historyEntryMinLen }
 }
 
+// HistoryAppend adds each command to the history file as it is entered,
+// rather than only when the history is saved, so that it is not lost if the
+// program ends without saving and is available to sessions started later.
+func HistoryAppend(historyAppend bool) Option {
+	return func(c *CLE) { c.historyAppend = historyAppend }
+}
+
+// HistoryFormat is the format the history file is written in, e.g.
+// ExtendedHistory to record when and where each command was entered.
+// (Default PlainHistory)
+func HistoryFormat(format HistoryFileFormat) Option {
+	return func(c *CLE) { c.historyFormat = format }
+}
+
+// History keeps the command history in store, in place of a history file
+// (see HistoryFile). The store is loaded by NewCLE, and is the application's
+// to close.
+func History(store HistoryStore) Option {
+	return func(c *CLE) { c.historyStore = store }
+}
+
+// HistoryControl sets the policies for saving commands in the history, like
+// bash's HISTCONTROL, e.g. IgnoreSpace|EraseDups. (Default IgnoreDups)
+func HistoryControl(policy HistoryPolicy) Option {
+	return func(c *CLE) { c.historyControl = policy }
+}
+
+// IgnorePatterns leaves commands matching any of the patterns out of the
+// history, e.g. to keep secrets out of it. A pattern between slashes is a
+// regular expression matching any part of a command ("/--token[= ]/"), and
+// any other a glob matching a whole command or any word of it
+// ("*password=*"), where * matches any characters, even /. A pattern that
+// cannot be compiled is left out, and the first read (see ReadLine) returns
+// its error.
+func IgnorePatterns(patterns []string) Option {
+	return func(c *CLE) { c.ignorePatterns = patterns }
+}
+
+// Redaction removes secrets from commands with the redactors, in order, before
+// they are saved in the history, e.g. Redaction(DefaultRedactors()...).
+func Redaction(redactors ...Redactor) Option {
+	return func(c *CLE) { c.redactors = redactors }
+}
+
+// HistoryEncryptionKey encrypts the history file (see HistoryFile) with the
+// key, using AES-GCM, which also detects any tampering with the file. The key
+// must be 16, 24 or 32 bytes long (for AES-128, AES-192 or AES-256), or the
+// history is neither loaded nor saved, and LoadHistory, SaveHistoryErr and
+// the first read (see ReadLine) return the error of the key. Nor is a history
+// file that cannot be decrypted (see ErrHistoryDecryption).
+func HistoryEncryptionKey(key []byte) Option {
+	return func(c *CLE) { c.historyKey = append(make([]byte, 0, len(key)), key...) }
+}
+
+// MigratePlainHistory has an encrypted history (see HistoryEncryptionKey) read
+// a history file that is not yet encrypted, which is encrypted the next time
+// commands are saved to it. Without it, such a file is an error.
+func MigratePlainHistory(migrate bool) Option {
+	return func(c *CLE) { c.migrateHistory = migrate }
+}
+
 func ReportErrors(reportErrors bool) Option {
 	return func(c *CLE) { c.reportErrors = reportErrors }
 }
@@ -24,6 +90,105 @@ func SearchModeChar(searchMode byte) Option {
 	return func(c *CLE) { c.searchModeChar = searchMode }
 }
 
+// KeyMapping replaces the default key bindings (LegacyKeyMap) with keyMap,
+// e.g. EmacsKeyMap().
+func KeyMapping(keyMap KeyMap) Option {
+	return func(c *CLE) {
+		c.keyMap = KeyMap{}
+		for key, action := range keyMap {
+			c.keyMap[key] = action
+		}
+	}
+}
+
+// Bindings adds to or overrides the key bindings of the key map. A binding
+// with an empty action removes the key's binding.
+func Bindings(bindings ...Binding) Option {
+	return func(c *CLE) { c.bindings = append(c.bindings, bindings...) }
+}
+
+// ViMode enables vi-style editing: each line starts in insert mode and
+// Escape switches to normal mode, as indicated before the prompt.
+func ViMode(viMode bool) Option {
+	return func(c *CLE) { c.viMode = viMode }
+}
+
+// Completion enables TAB completion of the input by completer.
+func Completion(completer Completer) Option {
+	return func(c *CLE) { c.completer = completer }
+}
+
+// Validation checks the input with validator when ENTER is pressed. Incomplete
+// input (e.g. with an unclosed quote or bracket) continues on a new line.
+func Validation(validator Validator) Option {
+	return func(c *CLE) { c.validator = validator }
+}
+
+// Mask is echoed by ReadPassword for each character of the secret typed.
+// (Default: nothing is echoed)
+func Mask(mask rune) Option {
+	return func(c *CLE) { c.mask = mask }
+}
+
+// Highlighting styles the input with highlighter as it is displayed.
+func Highlighting(highlighter Highlighter) Option {
+	return func(c *CLE) { c.highlighter = highlighter }
+}
+
+// AutoSuggest suggests the rest of the most recent command in the history
+// that begins with the input, dimmed after the cursor. Right arrow or END
+// accepts the suggestion, ALT-F the next word of it.
+func AutoSuggest(autoSuggest bool) Option {
+	return func(c *CLE) { c.autoSuggest = autoSuggest }
+}
+
+// ContinuationPrompt is displayed at the beginning of each continued line of
+// multi-line input. (Default "> ")
+func ContinuationPrompt(prompt string) Option {
+	return func(c *CLE) { c.continuationPrompt = prompt }
+}
+
+// EscapeTimeout is how long to wait for the rest of an escape sequence
+// (e.g. ESC [ A for the up arrow) before taking the input as it stands, so
+// that ESC pressed alone is the Escape key. (Default 100ms)
+func EscapeTimeout(timeout time.Duration) Option {
+	return func(c *CLE) { c.escapeTimeout = timeout }
+}
+
+// BracketedPaste enables the terminal's bracketed paste mode during each
+// read, so that pasted text is inserted as a whole: a line break in it does
+// not end the input. (Default true)
+func BracketedPaste(enabled bool) Option {
+	return func(c *CLE) { c.bracketedPaste = enabled }
+}
+
+// HandledSignals tells the CLE that the application watches SIGTERM and
+// SIGHUP itself (see signal.Notify), so that the CLE, which restores the
+// terminal when one is received during a read, leaves the rest to the
+// application instead of delivering the signal again to end the process.
+func HandledSignals(handled bool) Option {
+	return func(c *CLE) { c.handledSignals = handled }
+}
+
+// Input reads keystrokes from reader instead of opening the TTY.
+// The reader is expected to deliver raw (unbuffered, unechoed) input;
+// combine with TerminalDevice to control the line discipline. The reader is
+// read in the background for the life of the CLE.
+func Input(reader io.Reader) Option {
+	return func(c *CLE) { c.input = reader }
+}
+
+// Output writes the prompt and edited line to writer instead of os.Stdout.
+func Output(writer io.Writer) Option {
+	return func(c *CLE) { c.output = writer }
+}
+
+// TerminalDevice uses terminal to switch modes and query the window size
+// during each read, in place of the TTY.
+func TerminalDevice(terminal Terminal) Option {
+	return func(c *CLE) { c.terminal = terminal }
+}
+
 // TestMode disables terminal output for testing
 func TestMode(testMode bool) Option {
 	return func(c *CLE) { c.testMode = testMode }
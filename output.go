@@ -0,0 +1,60 @@
+package cle
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// Printf formats according to a format specifier, as fmt.Printf does, and
+// writes the message with Write.
+func (this *CLE) Printf(format string, args ...interface{}) (int, error) {
+	return fmt.Fprintf(this, format, args...)
+}
+
+// Write writes the message to the output, e.g. from a goroutine logging
+// while the user types. During a read the message is written above the
+// prompt, which is then redrawn along with the input and cursor, on a line of
+// its own. It may be called from any goroutine, but not from a Completer,
+// Validator or Highlighter.
+func (this *CLE) Write(message []byte) (int, error) {
+	this.displayLock.Lock()
+	defer this.displayLock.Unlock()
+
+	if !this.displaying {
+		return this.output.Write(message)
+	}
+
+	this.erase()
+	text := bytes.ReplaceAll(bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+	if !bytes.HasSuffix(text, []byte("\n")) {
+		text = append(text, '\r', '\n')
+	}
+	if _, err := this.output.Write(text); err != nil {
+		return 0, err
+	}
+	this.repaint()
+	return len(message), nil
+}
+
+// awaitInput reads the input of the read, allowing messages to be written
+// (see Write) while it waits.
+func (this *CLE) awaitInput(timeout time.Duration) ([]byte, error) {
+	if this.displaying {
+		this.displayLock.Unlock()
+		defer this.displayLock.Lock()
+	}
+	return this.reader.read(timeout)
+}
+
+// erase clears the prompt and input from the display, leaving the terminal
+// cursor where the prompt began.
+func (this *CLE) erase() {
+	if !this.testMode {
+		if this.rendered.cursorRow > 0 {
+			fmt.Fprintf(this.output, "\x1b[%dA", this.rendered.cursorRow) // VT100 cursor up to the first row
+		}
+		fmt.Fprint(this.output, "\r\x1b[J") // VT100 clear to end of screen
+	}
+	this.rendered = rendering{}
+}
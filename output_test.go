@@ -0,0 +1,67 @@
+package cle
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestOutputFixture(t *testing.T) {
+	gunit.Run(new(OutputFixture), t)
+}
+
+type OutputFixture struct {
+	*gunit.Fixture
+	output *bytes.Buffer
+}
+
+func (this *OutputFixture) Setup() {
+	this.output = new(bytes.Buffer)
+}
+
+func (this *OutputFixture) TestWriteWithoutRead() {
+	cleObj := NewCLE(Output(this.output))
+
+	count, err := cleObj.Printf("%d messages\n", 2)
+	this.So(count, should.Equal, 11)
+	this.So(err, should.BeNil)
+	this.So(this.output.String(), should.Equal, "2 messages\n")
+}
+
+func (this *OutputFixture) TestWriteAbovePrompt() {
+	cleObj := NewCLE(Output(this.output), BracketedPaste(false))
+	cleObj.displaying = true
+	cleObj.prompt = "> "
+	cleObj.data = []rune("abc")
+	cleObj.cursorPosition = 1
+	cleObj.rendered = rendering{cursorRow: 1, rows: 2}
+
+	count, err := cleObj.Write([]byte("one\ntwo"))
+	this.So(count, should.Equal, 7)
+	this.So(err, should.BeNil)
+	this.So(this.output.String(), should.Equal, "\x1b[1A\r\x1b[J"+"one\r\ntwo\r\n"+"\r\x1b[J> abc\r\x1b[3C")
+}
+
+func (this *OutputFixture) TestWriteDuringRead() {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	cleObj := NewCLE(Input(reader), Output(this.output), TerminalDevice(new(FakeTerminal)), BracketedPaste(false))
+	result := make(chan string, 1)
+	go func() {
+		line, _ := cleObj.ReadLine("> ")
+		result <- line
+	}()
+	for !isDisplaying(cleObj) {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, _ = cleObj.Printf("hello\n")
+	_, _ = writer.Write([]byte("abc\r"))
+
+	this.So(<-result, should.Equal, "abc")
+	this.So(this.output.String(), should.StartWith, "\r\x1b[J> \r\x1b[2C"+"\r\x1b[J"+"hello\r\n"+"\r\x1b[J> \r\x1b[2C")
+}
@@ -0,0 +1,120 @@
+package cle
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// ReadPassword displays the prompt and returns the secret entered by the
+// user, echoing the mask character (see Mask) for each character typed, or
+// else nothing. The secret is not saved in the history, and the history
+// cannot be recalled or searched during the read. The CLE's copies of the
+// input are zeroed before ReadPassword returns; the caller should zero the
+// secret returned once done with it. The errors are those of ReadLine.
+func (this *CLE) ReadPassword(prompt string) ([]byte, error) {
+	kills, register, lastChange := this.kills.entries, this.vi.register, this.vi.lastChange
+	this.kills.entries = copyRunes(kills)
+	this.vi.register = append([]rune(nil), register...)
+	this.vi.lastChange = append([]Key(nil), lastChange...)
+	this.password = true
+	defer func() {
+		this.password = false
+		this.zeroInput()
+		this.kills.entries, this.vi.register, this.vi.lastChange = kills, register, lastChange
+		this.kills.killed, this.kills.yanked = false, false
+	}()
+
+	return this.readLine(context.Background(), prompt)
+}
+
+// passwordAction reports whether the action is performed during ReadPassword;
+// the actions of the history are ignored, as is completion, which would pass
+// the secret to the Completer and could list it.
+func passwordAction(action Action) bool {
+	switch action {
+	case PreviousHistory, NextHistory, ReverseSearchHistory, ForwardSearchHistory, Complete:
+		return false
+	default:
+		return true
+	}
+}
+
+// masked returns the cells displayed for the input of ReadPassword, one mask
+// character for each character of the input, and the cursor position among
+// them.
+func (this *CLE) masked() (cells []cell, cursor int) {
+	if this.mask == 0 {
+		return nil, 0
+	}
+	lengths, _ := graphemes(this.data)
+	position := 0
+	for _, length := range lengths {
+		if position < this.cursorPosition {
+			cursor++
+		}
+		cells = append(cells, cell{r: this.mask, width: 1})
+		position += length
+	}
+	return cells, cursor
+}
+
+// zeroInput overwrites the input of ReadPassword wherever the CLE has kept
+// it: the line, its edit history, the kill ring and the vi register.
+func (this *CLE) zeroInput() {
+	zeroRunes(this.data)
+	this.data = this.data[:0]
+	this.cursorPosition = 0
+	for _, state := range append(this.edits.undo, this.edits.redo...) {
+		zeroRunes(state.data)
+	}
+	if this.edits.group != nil {
+		zeroRunes(this.edits.group.data)
+	}
+	this.edits = editHistory{}
+	for _, entry := range this.kills.entries {
+		zeroRunes(entry)
+	}
+	zeroRunes(this.vi.register)
+	for i := range this.vi.command {
+		this.vi.command[i] = Key{}
+	}
+	for i := range this.vi.lastChange {
+		this.vi.lastChange[i] = Key{}
+	}
+	this.vi = vi{}
+}
+
+////////////////////////////////////////////
+
+// runeBytes returns the UTF-8 encoding of the runes, without the intermediate
+// string of []byte(string(runes)), which could not be zeroed.
+func runeBytes(runes []rune) []byte {
+	encoded := make([]byte, 0, len(runes)*utf8.UTFMax)
+	buffer := make([]byte, utf8.UTFMax)
+	for _, r := range runes {
+		encoded = append(encoded, buffer[:utf8.EncodeRune(buffer, r)]...)
+	}
+	zeroBytes(buffer)
+	return encoded
+}
+
+func copyRunes(entries [][]rune) [][]rune {
+	copies := make([][]rune, len(entries))
+	for i, entry := range entries {
+		copies[i] = append([]rune(nil), entry...)
+	}
+	return copies
+}
+
+func zeroRunes(runes []rune) {
+	runes = runes[:cap(runes)]
+	for i := range runes {
+		runes[i] = 0
+	}
+}
+
+func zeroBytes(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
@@ -0,0 +1,134 @@
+package cle
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestPasswordFixture(t *testing.T) {
+	gunit.Run(new(PasswordFixture), t)
+}
+
+type PasswordFixture struct {
+	*gunit.Fixture
+	output *bytes.Buffer
+}
+
+func (this *PasswordFixture) Setup() {
+	this.output = new(bytes.Buffer)
+}
+
+func (this *PasswordFixture) newCLE(input string, options ...Option) *CLE {
+	options = append(options, Input(strings.NewReader(input)), Output(this.output), BracketedPaste(false))
+	cleObj := NewCLE(options...)
+	cleObj.history.commands = [][]byte{[]byte("previous command")}
+	return cleObj
+}
+
+func (this *PasswordFixture) TestSecretIsReturnedAndNotSaved() {
+	cleObj := this.newCLE(":s3cr\x7Fret!\r")
+
+	secret, err := cleObj.ReadPassword("Password: ")
+	this.So(string(secret), should.Equal, ":s3cret!")
+	this.So(err, should.BeNil)
+	this.So(cleObj.history.commands, should.Resemble, [][]byte{[]byte("previous command")})
+	this.So(this.output.String(), should.NotContainSubstring, "s3c")
+}
+
+func (this *PasswordFixture) TestHistoryCannotBeRecalledOrSearched() {
+	cleObj := this.newCLE("\x1b[A\x12prev\r", AutoSuggest(true))
+
+	secret, err := cleObj.ReadPassword("Password: ")
+	this.So(string(secret), should.Equal, "prev")
+	this.So(err, should.BeNil)
+	this.So(this.output.String(), should.NotContainSubstring, "previous")
+}
+
+func (this *PasswordFixture) TestViHistoryCannotBeRecalled() {
+	cleObj := this.newCLE("", ViMode(true))
+	cleObj.password = true
+	cleObj.data = []rune("secret")
+	cleObj.cursorPosition = 6
+
+	cleObj.handleKey(Key{Code: ESCAPE_KEY})
+	cleObj.handleKey(Key{Code: 'k'})
+	cleObj.handleKey(Key{Code: 'j'})
+	this.So(string(cleObj.data), should.Equal, "secret")
+	this.So(cleObj.history.currentPosition, should.Equal, 0)
+}
+
+func (this *PasswordFixture) TestSecretIsNotCompleted() {
+	completed := false
+	completer := CompleterFunc(func(input []rune, cursor int) ([]string, int, int) {
+		completed = true
+		return []string{"secretive", "secretly"}, 0, cursor
+	})
+	cleObj := this.newCLE("secret		", Completion(completer))
+
+	secret, err := cleObj.ReadPassword("> ")
+	this.So(string(secret), should.Equal, "secret")
+	this.So(err, should.BeNil)
+	this.So(completed, should.BeFalse)
+	this.So(this.output.String(), should.NotContainSubstring, "secret")
+}
+
+func (this *PasswordFixture) TestMask() {
+	cleObj := this.newCLE("", Mask('*'))
+	cleObj.password = true
+	cleObj.prompt = "> "
+	cleObj.data = []rune("ab日é")
+	cleObj.cursorPosition = 3
+
+	cleObj.repaint()
+	this.So(this.output.String(), should.Equal, "\r\x1b[J> ****\r\x1b[5C")
+}
+
+func (this *PasswordFixture) TestNoMaskEchoesNothing() {
+	cleObj := this.newCLE("abc\r")
+
+	_, _ = cleObj.ReadPassword("> ")
+	this.So(this.output.String(), should.Equal, "\r\x1b[J> \r\x1b[2C"+strings.Repeat("\r\x1b[J> \r\x1b[2C", 3)+"\n\r")
+}
+
+func (this *PasswordFixture) TestInputIsZeroed() {
+	cleObj := this.newCLE("secret\x17\x19\x19\r")
+
+	secret, _ := cleObj.ReadPassword("> ")
+	this.So(string(secret), should.Equal, "secretsecret")
+	this.So(cleObj.data, should.BeEmpty)
+	this.So(cleObj.edits, should.Resemble, editHistory{})
+	this.So(cleObj.kills.entries, should.BeEmpty)
+	this.So(cleObj.password, should.BeFalse)
+}
+
+func (this *PasswordFixture) TestKillRingIsKept() {
+	cleObj := this.newCLE("secret\x01\x0b\r")
+	cleObj.kills.entries = [][]rune{[]rune("killed")}
+
+	_, _ = cleObj.ReadPassword("> ")
+	this.So(cleObj.kills.entries, should.Resemble, [][]rune{[]rune("killed")})
+}
+
+func (this *PasswordFixture) TestViRegisterIsKept() {
+	cleObj := this.newCLE("secret\r", ViMode(true))
+	cleObj.vi.register = []rune("yanked")
+	cleObj.vi.lastChange = []Key{{Code: 'x'}}
+
+	_, _ = cleObj.ReadPassword("> ")
+	this.So(cleObj.vi.register, should.Resemble, []rune("yanked"))
+	this.So(cleObj.vi.lastChange, should.Resemble, []Key{{Code: 'x'}})
+}
+
+func (this *PasswordFixture) TestReadError() {
+	cleObj := NewCLE(Input(strings.NewReader("abc")), Output(io.Discard))
+
+	secret, err := cleObj.ReadPassword("> ")
+	this.So(secret, should.BeNil)
+	this.So(err, should.Equal, ErrEOF)
+	this.So(cleObj.data, should.BeEmpty)
+}
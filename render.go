@@ -0,0 +1,186 @@
+package cle
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// cell is a rune of the rendered input along with the SGR sequence it is
+// displayed with and the columns it takes up: the width of its grapheme
+// cluster for the first rune of the cluster, and 0 for the rest (e.g. a
+// combining accent).
+type cell struct {
+	r     rune
+	style string
+	width int
+}
+
+// rendering records the layout of the last repaint so the next one can
+// return to its first row, however many rows the input wrapped onto.
+type rendering struct {
+	cursorRow int
+	rows      int
+}
+
+// repaint redraws the prompt and input, wrapping at the terminal width and
+// continuing each line of multi-line input after the continuation prompt,
+// and leaves the terminal cursor at the cursor position.
+func (this *CLE) repaint() {
+	if this.testMode {
+		return
+	}
+
+	prompt := this.prompt
+	if this.viMode {
+		prompt = this.viModeIndicator() + prompt
+	}
+	if this.password {
+		cells, cursor := this.masked()
+		this.draw(prompt, cells, cursor)
+		return
+	}
+
+	cells := this.cells(this.suggestion())
+	this.highlight(cells)
+	for i := len(this.data); i < len(cells); i++ {
+		cells[i].style = SGR_DIM
+	}
+	if this.search.active {
+		prompt = this.incrementalSearchPrompt()
+		start, end := this.incrementalSearchHighlight()
+		for i := start; i < end; i++ {
+			cells[i].style = SGR_REVERSE
+		}
+	}
+	this.draw(prompt, cells, this.cursorPosition)
+}
+
+// cells returns the cells of the input followed by the suggestion.
+func (this *CLE) cells(suggestion []rune) []cell {
+	runes := append(append([]rune(nil), this.data...), suggestion...)
+	cells := make([]cell, len(runes))
+	for i, r := range runes {
+		cells[i] = cell{r: r}
+	}
+	lengths, widths := graphemes(runes)
+	position := 0
+	for i, length := range lengths {
+		cells[position].width = widths[i]
+		position += length
+	}
+	return cells
+}
+
+func (this *CLE) draw(prompt string, cells []cell, cursor int) {
+	width := this.terminalWidth()
+	output := new(bytes.Buffer)
+	if this.rendered.cursorRow > 0 {
+		fmt.Fprintf(output, "\x1b[%dA", this.rendered.cursorRow) // VT100 cursor up to the first row
+	}
+	output.WriteString("\r\x1b[J") // VT100 clear to end of screen
+
+	layout := &layout{width: width}
+	layout.writePrompt(output, prompt)
+	cursorRow, cursorCol := -1, -1
+	style := ""
+	for i, c := range cells {
+		if i == cursor {
+			cursorRow, cursorCol = layout.position()
+		}
+		if c.style != style {
+			output.WriteString(SGR_RESET + c.style)
+			style = c.style
+		}
+		if c.r == '\n' {
+			output.WriteString("\r\n")
+			layout.newline()
+			layout.writePrompt(output, this.continuationPrompt)
+			continue
+		}
+		layout.advance(c.width)
+		output.WriteRune(c.r)
+	}
+	if style != "" {
+		output.WriteString(SGR_RESET)
+	}
+	if layout.column >= width { // leave the pending wrap at the right margin so the cursor math holds
+		output.WriteString("\r\n")
+		layout.newline()
+	}
+	if cursorRow < 0 {
+		cursorRow, cursorCol = layout.position()
+	}
+
+	if up := layout.row - cursorRow; up > 0 {
+		fmt.Fprintf(output, "\x1b[%dA", up)
+	}
+	output.WriteString("\r")
+	if cursorCol > 0 {
+		fmt.Fprintf(output, "\x1b[%dC", cursorCol)
+	}
+
+	this.rendered = rendering{cursorRow: cursorRow, rows: layout.row + 1}
+	_, _ = this.output.Write(output.Bytes())
+}
+
+// layout tracks the terminal cursor as characters are written, wrapping at
+// the right margin the way the terminal does, including before a wide
+// character that does not fit on the row.
+type layout struct {
+	width  int
+	row    int
+	column int
+}
+
+func (this *layout) advance(columns int) {
+	if this.column+columns > this.width {
+		this.row++
+		this.column = 0
+	}
+	this.column += columns
+}
+
+func (this *layout) newline() {
+	this.row++
+	this.column = 0
+}
+
+// position reports where the next character will be written.
+func (this *layout) position() (row, column int) {
+	if this.column >= this.width {
+		return this.row + 1, 0
+	}
+	return this.row, this.column
+}
+
+func (this *layout) writePrompt(output *bytes.Buffer, prompt string) {
+	output.WriteString(prompt)
+	_, widths := graphemes([]rune(stripEscapes(prompt)))
+	for _, width := range widths {
+		this.advance(width)
+	}
+}
+
+////////////////////////////////////////////
+
+// stripEscapes removes ANSI escape sequences (e.g. colors in the prompt),
+// which take up no space on the screen.
+func stripEscapes(text string) string {
+	output := make([]rune, 0, len(text))
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != ESCAPE_KEY {
+			output = append(output, runes[i])
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == ARROW_KEY_INDICATOR { // CSI: parameters up to a final byte
+			i += 2
+			for i < len(runes) && (runes[i] < 0x40 || runes[i] > 0x7E) {
+				i++
+			}
+		} else {
+			i++
+		}
+	}
+	return string(output)
+}
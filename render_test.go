@@ -0,0 +1,96 @@
+package cle
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestRenderFixture(t *testing.T) {
+	gunit.Run(new(RenderFixture), t)
+}
+
+type RenderFixture struct {
+	*gunit.Fixture
+	output *bytes.Buffer
+	cle    *CLE
+}
+
+func (this *RenderFixture) Setup() {
+	this.output = new(bytes.Buffer)
+	this.cle = NewCLE(Output(this.output), ContinuationPrompt(".. "))
+	this.cle.control = &FakeTerminal{columns: 10}
+	this.cle.prompt = "> "
+}
+
+func (this *RenderFixture) repaint(data string, cursor int) string {
+	this.output.Reset()
+	this.cle.data = []rune(data)
+	this.cle.cursorPosition = cursor
+	this.cle.repaint()
+	return this.output.String()
+}
+
+func (this *RenderFixture) TestSingleRow() {
+	this.So(this.repaint("abc", 1), should.Equal, "\r\x1b[J> abc\r\x1b[3C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 0, rows: 1})
+}
+
+func (this *RenderFixture) TestWrappedInput() {
+	this.So(this.repaint("abcdefghijkl", 12), should.Equal, "\r\x1b[J> abcdefghijkl\r\x1b[4C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 1, rows: 2})
+
+	// the next repaint returns to the first row before clearing
+	this.So(this.repaint("abcdefghijkl", 0), should.Equal, "\x1b[1A\r\x1b[J> abcdefghijkl\x1b[1A\r\x1b[2C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 0, rows: 2})
+}
+
+func (this *RenderFixture) TestInputFillingTheRowMovesCursorToNextRow() {
+	this.So(this.repaint("abcdefgh", 8), should.Equal, "\r\x1b[J> abcdefgh\r\n\r")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 1, rows: 2})
+}
+
+func (this *RenderFixture) TestMultiLineInputUsesContinuationPrompt() {
+	this.So(this.repaint("ab\ncd", 4), should.Equal, "\r\x1b[J> ab\r\n.. cd\r\x1b[4C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 1, rows: 2})
+}
+
+func (this *RenderFixture) TestWideCharactersTakeTwoColumns() {
+	this.So(this.repaint("日本", 1), should.Equal, "\r\x1b[J> 日本\r\x1b[4C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 0, rows: 1})
+}
+
+func (this *RenderFixture) TestWideCharacterWrapsWhenItDoesNotFit() {
+	// "> abcde" leaves 3 columns, "日" fits in 2 of them, "本" goes to the next row.
+	this.So(this.repaint("abcde日本", 7), should.Equal, "\r\x1b[J> abcde日本\r\x1b[2C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 1, rows: 2})
+}
+
+func (this *RenderFixture) TestCombiningMarksTakeNoColumns() {
+	this.So(this.repaint("e\u0301x", 2), should.Equal, "\r\x1b[J> e\u0301x\r\x1b[3C")
+}
+
+func (this *RenderFixture) TestCRLFMovesBelowRenderedInput() {
+	this.repaint("abcdefghijklmnopqrst", 0)
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 0, rows: 3})
+
+	this.output.Reset()
+	this.cle.crlf()
+	this.So(this.output.String(), should.Equal, "\x1b[2B\n\r")
+	this.So(this.cle.rendered, should.Resemble, rendering{})
+}
+
+func (this *RenderFixture) TestStyledCells() {
+	this.cle.search = incrementalSearch{active: true, query: []rune("b"), position: 1}
+	this.cle.history.commands = [][]byte{[]byte("abc")}
+	this.So(this.repaint("abc", 1), should.Equal,
+		"\r\x1b[J(reverse-i-search)'b': a"+SGR_RESET+SGR_REVERSE+"b"+SGR_RESET+"c\r\x1b[4C")
+	this.So(this.cle.rendered, should.Resemble, rendering{cursorRow: 2, rows: 3})
+}
+
+func (this *RenderFixture) TestStripEscapes() {
+	this.So(stripEscapes("\x1b[1;32mgreen\x1b[0m> "), should.Equal, "green> ")
+	this.So(stripEscapes("plain"), should.Equal, "plain")
+}
@@ -0,0 +1,126 @@
+package cle
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// suspendProcess stops the process group, as CTL-Z does in cooked mode, and
+// returns once the process is continued (SIGCONT), e.g. by the shell's fg.
+var suspendProcess = func() error {
+	return syscall.Kill(0, syscall.SIGTSTP)
+}
+
+// interrupt abandons the line, as CTL-C does in cooked mode.
+func (this *CLE) interrupt() error {
+	this.crlf()
+	return ErrInterrupted
+}
+
+// suspend returns the TTY to cooked mode and suspends the program; when the
+// program is continued, the TTY returns to raw mode and the line is redrawn.
+// Input from other devices (see Input) cannot suspend the program.
+func (this *CLE) suspend() error {
+	if this.tty == nil {
+		return nil
+	}
+
+	this.crlf()
+	this.handleError(this.leaveRawMode())
+
+	err := suspendProcess()
+
+	if rawErr := this.enterRawMode(); rawErr != nil {
+		return rawErr
+	}
+	this.repaint()
+	return err
+}
+
+// watchResize repaints the line whenever the TTY is resized (SIGWINCH), for
+// the new width, until the returned function is called.
+func (this *CLE) watchResize() (stop func()) {
+	if this.tty == nil {
+		return func() {}
+	}
+	return watchSignals(func(os.Signal) bool {
+		this.resize()
+		return true
+	}, syscall.SIGWINCH)
+}
+
+// watchSignals calls handle for each of the signals received until handle
+// returns false or the returned function is called, which waits for handle
+// to return.
+func watchSignals(handle func(os.Signal) bool, watched ...os.Signal) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	signal.Notify(signals, watched...)
+	go func() {
+		defer close(stopped)
+		defer signal.Stop(signals)
+		for {
+			select {
+			case received := <-signals:
+				if !handle(received) {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// watchSignalOnce calls handle for the first of the signals received, if one
+// is received before the returned function is called, which waits for handle
+// to return. The signals are no longer watched when handle is called, so that
+// handle can deliver the signal again (see resignal) to take its usual effect.
+func watchSignalOnce(handle func(os.Signal), watched ...os.Signal) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	signal.Notify(signals, watched...)
+	go func() {
+		defer close(stopped)
+		select {
+		case received := <-signals:
+			signal.Stop(signals)
+			handle(received)
+		case <-done:
+			signal.Stop(signals)
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// resignal delivers the signal received to the process again, after it is no
+// longer watched.
+var resignal = func(received os.Signal) {
+	if number, ok := received.(syscall.Signal); ok {
+		_ = syscall.Kill(os.Getpid(), number)
+	}
+}
+
+// resize has the read loop repaint the line at its next opportunity.
+func (this *CLE) resize() {
+	atomic.StoreInt32(&this.resized, 1)
+	this.reader.interrupt()
+}
+
+// handledResize repaints the line if the terminal was resized.
+func (this *CLE) handledResize() bool {
+	if !atomic.CompareAndSwapInt32(&this.resized, 1, 0) {
+		return false
+	}
+	this.repaint()
+	return true
+}
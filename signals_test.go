@@ -0,0 +1,89 @@
+package cle
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestSignalsFixture(t *testing.T) {
+	gunit.Run(new(SignalsFixture), t)
+}
+
+type SignalsFixture struct {
+	*gunit.Fixture
+}
+
+func (this *SignalsFixture) TestInterrupt() {
+	cleObj := NewCLE(TestMode(true))
+	cleObj.data = []rune("some data")
+	this.So(cleObj.handleKey(Ctrl('c')), should.Equal, ErrInterrupted)
+
+	cleObj = NewCLE(Input(strings.NewReader("abc\x03def\r")), Output(io.Discard))
+	line, err := cleObj.ReadLine("> ")
+	this.So(err, should.Equal, ErrInterrupted)
+	this.So(line, should.BeEmpty)
+
+	line, err = cleObj.ReadLine("> ") // the next line starts afresh
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "def")
+}
+
+func (this *SignalsFixture) TestSuspend() {
+	defer func(original func() error) { suspendProcess = original }(suspendProcess)
+	output, terminal := new(bytes.Buffer), new(FakeTerminal)
+	var callsBeforeSuspending []string
+	suspendProcess = func() error {
+		callsBeforeSuspending = append(terminal.calls, "suspend")
+		return nil
+	}
+
+	cleObj := NewCLE(Output(output))
+	cleObj.tty, cleObj.control, cleObj.rawMode = new(ttyTerminal), terminal, true
+	cleObj.prompt, cleObj.data, cleObj.cursorPosition = "> ", []rune("abc"), 3
+
+	this.So(cleObj.handleKey(Ctrl('z')), should.BeNil)
+	this.So(callsBeforeSuspending, should.Resemble, []string{"Restore", "suspend"})
+	this.So(terminal.calls, should.Resemble, []string{"Restore", "RawMode"})
+	this.So(output.String(), should.StartWith, "\n\r"+BRACKETED_PASTE_OFF)
+	this.So(output.String(), should.EndWith, BRACKETED_PASTE_ON+"\r\x1b[J> abc\r\x1b[5C")
+	this.So(string(cleObj.data), should.Equal, "abc")
+}
+
+func (this *SignalsFixture) TestSuspendOnlyFromTheTTY() {
+	defer func(original func() error) { suspendProcess = original }(suspendProcess)
+	suspended := false
+	suspendProcess = func() error {
+		suspended = true
+		return nil
+	}
+
+	cleObj := NewCLE(TestMode(true))
+	cleObj.control = new(FakeTerminal)
+	this.So(cleObj.handleKey(Ctrl('z')), should.BeNil)
+	this.So(suspended, should.BeFalse)
+}
+
+func (this *SignalsFixture) TestResizeInterruptsReadAndRepaints() {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	output := new(bytes.Buffer)
+	cleObj := NewCLE(Input(reader), Output(output))
+	this.So(cleObj.openTty(), should.BeNil)
+	defer cleObj.closeTty()
+	cleObj.prompt = "> "
+
+	cleObj.resize()
+	input, err := cleObj.reader.read(-1)
+	this.So(input, should.BeNil)
+	this.So(err, should.BeNil)
+
+	output.Reset()
+	this.So(cleObj.handledResize(), should.BeTrue)
+	this.So(output.String(), should.Equal, "\r\x1b[J> \r\x1b[2C")
+	this.So(cleObj.handledResize(), should.BeFalse)
+}
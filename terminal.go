@@ -0,0 +1,111 @@
+package cle
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/term"
+	"golang.org/x/sys/unix"
+)
+
+// Terminal controls the line discipline of the device the CLE reads from.
+// Implementations let the CLE run on devices other than the controlling
+// terminal, e.g. an SSH channel or a websocket console (see TerminalDevice).
+type Terminal interface {
+	// RawMode switches the device to raw (non-canonical, no echo) mode.
+	RawMode() error
+
+	// Restore returns the device to the mode it was in before RawMode.
+	Restore() error
+
+	// Size reports the dimensions of the device in character cells.
+	Size() (columns, rows int, err error)
+}
+
+// ttyTerminal is the default Terminal, backed by the controlling terminal (TTY).
+type ttyTerminal struct {
+	*term.Term
+	file *os.File // used for window size queries, which term.Term does not expose
+	wake *os.File // written by interrupt to end a read in progress
+	woke *os.File // the read end of wake
+}
+
+func openTtyTerminal(name string) (*ttyTerminal, error) {
+	device, err := term.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		_ = device.Close()
+		return nil, err
+	}
+	woke, wake, err := os.Pipe()
+	if err != nil {
+		_ = file.Close()
+		_ = device.Close()
+		return nil, err
+	}
+	return &ttyTerminal{Term: device, file: file, wake: wake, woke: woke}, nil
+}
+
+func (this *ttyTerminal) RawMode() error {
+	return term.RawMode(this.Term)
+}
+
+// read waits at most timeout (indefinitely when negative) for input from
+// the terminal, which term.Term cannot do without changing the line
+// discipline, by polling the device first.
+func (this *ttyTerminal) read(timeout time.Duration) ([]byte, error) {
+	milliseconds := -1
+	if timeout >= 0 {
+		milliseconds = int(timeout / time.Millisecond)
+	}
+	poll := []unix.PollFd{
+		{Fd: int32(this.file.Fd()), Events: unix.POLLIN},
+		{Fd: int32(this.woke.Fd()), Events: unix.POLLIN},
+	}
+	ready, err := unix.Poll(poll, milliseconds)
+	if err == unix.EINTR {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if poll[1].Revents != 0 {
+		_, err = this.woke.Read(make([]byte, 64))
+		return nil, err
+	}
+	if ready == 0 {
+		return nil, nil
+	}
+
+	buffer := make([]byte, 256)
+	numRead, err := this.Term.Read(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer[:numRead], nil
+}
+
+func (this *ttyTerminal) interrupt() {
+	_, _ = this.wake.Write([]byte{0})
+}
+
+func (this *ttyTerminal) Size() (columns, rows int, err error) {
+	size, err := unix.IoctlGetWinsize(int(this.file.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(size.Col), int(size.Row), nil
+}
+
+func (this *ttyTerminal) Close() error {
+	_ = this.wake.Close()
+	_ = this.woke.Close()
+	fileErr := this.file.Close()
+	if err := this.Term.Close(); err != nil {
+		return err
+	}
+	return fileErr
+}
@@ -0,0 +1,84 @@
+package cle
+
+// editHistory records the input before each edit of the line, so that edits
+// can be undone and redone. Consecutive characters typed are one edit, as are
+// a vi command (including the text it inserts) and an incremental search.
+type editHistory struct {
+	undo      []lineState
+	redo      []lineState
+	inserting bool       // the last edit typed characters, which further characters extend
+	group     *lineState // the input before the edit in progress
+	restored  bool       // the last key undid or redid an edit
+}
+
+type lineState struct {
+	data   []rune
+	cursor int
+}
+
+func (this *CLE) snapshot() lineState {
+	return lineState{data: append([]rune(nil), this.data...), cursor: this.cursorPosition}
+}
+
+func (this *CLE) restore(state lineState) {
+	this.data = append(this.data[:0], state.data...)
+	this.cursorPosition = clamp(state.cursor, 0, len(this.data))
+}
+
+// recordEdit records the input before the key just handled, if the key
+// changed the input.
+func (this *CLE) recordEdit(before lineState, key Key) {
+	if this.edits.restored {
+		this.edits.restored, this.edits.inserting = false, false
+		return
+	}
+	if this.search.active || (this.viMode && this.vi.command != nil) {
+		if this.edits.group == nil {
+			this.edits.group = &before
+		}
+		return
+	}
+
+	typed := key.isCharacter() && !this.vi.normal &&
+		len(this.data) == len(before.data)+1 && this.cursorPosition == before.cursor+1
+	if this.edits.group != nil {
+		before, typed = *this.edits.group, false
+		this.edits.group = nil
+	}
+
+	if string(before.data) == string(this.data) {
+		this.edits.inserting = false
+		return
+	}
+	if !typed || !this.edits.inserting {
+		this.edits.undo = append(this.edits.undo, before)
+	}
+	this.edits.redo = nil
+	this.edits.inserting = typed
+}
+
+// undo restores the input as it was before the last edit.
+func (this *CLE) undo() error {
+	if len(this.edits.undo) == 0 {
+		return nil
+	}
+	this.edits.redo = append(this.edits.redo, this.snapshot())
+	this.restore(this.edits.undo[len(this.edits.undo)-1])
+	this.edits.undo = this.edits.undo[:len(this.edits.undo)-1]
+	this.edits.restored = true
+	this.repaint()
+	return nil
+}
+
+// redo restores the input as it was before the last undo.
+func (this *CLE) redo() error {
+	if len(this.edits.redo) == 0 {
+		return nil
+	}
+	this.edits.undo = append(this.edits.undo, this.snapshot())
+	this.restore(this.edits.redo[len(this.edits.redo)-1])
+	this.edits.redo = this.edits.redo[:len(this.edits.redo)-1]
+	this.edits.restored = true
+	this.repaint()
+	return nil
+}
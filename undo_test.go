@@ -0,0 +1,103 @@
+package cle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestUndoFixture(t *testing.T) {
+	gunit.Run(new(UndoFixture), t)
+}
+
+type UndoFixture struct {
+	*gunit.Fixture
+	*lineEditing
+}
+
+func (this *UndoFixture) Setup() {
+	this.lineEditing = newLineEditing(this.Fixture, NewCLE(TestMode(true)))
+}
+
+func (this *UndoFixture) TestUndoTypingAsOneEdit() {
+	this.typeText("hello world")
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("", 0)
+}
+
+func (this *UndoFixture) TestUndoDeletions() {
+	this.typeText("one two three")
+	this.typeKeys(Ctrl('w'))
+	this.assertLine("one two ", 8)
+	this.typeKeys(Ctrl('a'), Ctrl('k'))
+	this.assertLine("", 0)
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("one two ", 0)
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("one two three", 13)
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("", 0)
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("", 0)
+}
+
+func (this *UndoFixture) TestCursorMovementEndsTyping() {
+	this.typeText("ac")
+	this.typeKeys(Key{Code: KeyLeft})
+	this.typeText("b")
+	this.assertLine("abc", 2)
+
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("ac", 1)
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("", 0)
+}
+
+func (this *UndoFixture) TestRedo() {
+	this.typeText("one two")
+	this.typeKeys(Ctrl('w'), Ctrl('_'), Ctrl('_'))
+	this.assertLine("", 0)
+
+	this.typeKeys(Alt('_'))
+	this.assertLine("one two", 7)
+	this.typeKeys(Alt('_'))
+	this.assertLine("one ", 4)
+	this.typeKeys(Alt('_'))
+	this.assertLine("one ", 4)
+}
+
+func (this *UndoFixture) TestEditDiscardsRedo() {
+	this.typeText("one")
+	this.typeKeys(Ctrl('_'))
+	this.typeText("two")
+	this.typeKeys(Alt('_'))
+	this.assertLine("two", 3)
+	this.So(this.cle.edits.redo, should.BeEmpty)
+}
+
+func (this *UndoFixture) TestUndoIncrementalSearchAsOneEdit() {
+	this.cle.history.commands = [][]byte{[]byte("first command")}
+	this.cle.history.currentPosition = 1
+	this.typeText("x")
+	this.typeKeys(Ctrl('r'))
+	this.typeText("first")
+	this.typeKeys(Key{Code: KeyEnd})
+	this.assertLine("first command", 13)
+
+	this.typeKeys(Ctrl('_'))
+	this.assertLine("x", 1)
+}
+
+func (this *UndoFixture) TestEditHistoryResetForEachLine() {
+	this.cle = NewCLE(TestMode(true), Input(strings.NewReader("one\x17\rtwo\x1f\x1f\r")), TerminalDevice(&FakeTerminal{}))
+
+	line, err := this.cle.ReadLine("> ")
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "")
+
+	line, err = this.cle.ReadLine("> ")
+	this.So(err, should.BeNil)
+	this.So(line, should.Equal, "")
+}
@@ -0,0 +1,50 @@
+package cle
+
+// Validator decides whether the input is complete when ENTER is pressed.
+type Validator interface {
+	// Validate reports whether input is ready to be submitted. When it is not,
+	// ENTER inserts a newline and editing continues on a continuation line.
+	Validate(input []rune) bool
+}
+
+// ValidatorFunc adapts an ordinary function to the Validator interface.
+type ValidatorFunc func(input []rune) bool
+
+func (this ValidatorFunc) Validate(input []rune) bool {
+	return this(input)
+}
+
+// BalancedValidator reports input as incomplete while it has an unclosed
+// quote (' or ") or an unmatched opening bracket ((, [ or {).
+func BalancedValidator() Validator {
+	return ValidatorFunc(func(input []rune) bool {
+		var open []rune
+		var quote rune
+		for _, r := range input {
+			switch {
+			case quote != 0:
+				if r == quote {
+					quote = 0
+				}
+			case r == '\'' || r == '"':
+				quote = r
+			case r == '(' || r == '[' || r == '{':
+				open = append(open, r)
+			case len(open) > 0 && r == closing(open[len(open)-1]):
+				open = open[:len(open)-1]
+			}
+		}
+		return quote == 0 && len(open) == 0
+	})
+}
+
+func closing(bracket rune) rune {
+	switch bracket {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	default:
+		return '}'
+	}
+}
@@ -0,0 +1,269 @@
+package cle
+
+const (
+	VI_INSERT_MODE_INDICATOR = "(ins) "
+	VI_NORMAL_MODE_INDICATOR = "(cmd) "
+)
+
+// vi is the state of the vi editing mode (see ViMode). Each line starts in
+// insert mode, where keys behave as usual; Escape switches to normal mode,
+// where keys are commands.
+type vi struct {
+	normal     bool
+	operator   rune   // the pending d, c or y, waiting for its motion
+	replacing  bool   // the pending r, waiting for the replacement character
+	register   []rune // the text last deleted or yanked, put back by p and P
+	command    []Key  // the keys of the command in progress, for repeating with .
+	changed    bool   // whether the command in progress changed the input
+	lastChange []Key  // the keys of the last command that changed the input
+}
+
+// resetVi starts a new line in insert mode. The register and the last change
+// are kept, so they can be used on later lines.
+func (this *CLE) resetVi() {
+	this.vi = vi{register: this.vi.register, lastChange: this.vi.lastChange}
+}
+
+// handleViKey handles every key in normal mode and Escape in insert mode,
+// reporting false for the keys left to the key map.
+func (this *CLE) handleViKey(key Key) (bool, error) {
+	if !this.vi.normal {
+		if this.vi.command != nil {
+			this.vi.command = append(this.vi.command, key)
+		}
+		if key != (Key{Code: ESCAPE_KEY}) {
+			return false, nil
+		}
+		this.viNormalMode()
+		this.finishViCommand()
+		this.repaint()
+		return true, nil
+	}
+
+	idle := this.vi.operator == 0 && !this.vi.replacing
+	if idle && key == (Key{Code: 'u'}) {
+		this.viUndo(this.undo)
+		return true, nil
+	}
+	if idle && key == Ctrl('r') {
+		this.viUndo(this.redo)
+		return true, nil
+	}
+	if idle && key == (Key{Code: '.'}) {
+		return true, this.viRepeat()
+	}
+	if idle {
+		this.vi.command, this.vi.changed = nil, false
+	}
+
+	this.vi.command = append(this.vi.command, key)
+	err := this.viCommand(key)
+	if this.vi.normal && this.vi.operator == 0 && !this.vi.replacing {
+		this.cursorPosition = this.normalCursor(this.cursorPosition)
+		this.finishViCommand()
+	}
+	if err == nil {
+		this.repaint()
+	}
+	return true, err
+}
+
+func (this *CLE) viCommand(key Key) error {
+	if this.vi.replacing {
+		this.vi.replacing = false
+		if key.isCharacter() && this.cursorPosition < len(this.data) {
+			this.data[this.cursorPosition] = key.Code
+			this.vi.changed = true
+		}
+		return nil
+	}
+
+	if operator := this.vi.operator; operator != 0 {
+		this.vi.operator = 0
+		if key.Code == operator { // dd, cc and yy operate on the whole line
+			this.viOperate(operator, 0, len(this.data))
+			return nil
+		}
+		if target, inclusive, ok := this.viMotion(key.Code, operator); ok {
+			start, end := this.cursorPosition, target
+			if start > end {
+				start, end = end, start
+			}
+			if inclusive {
+				end = min(end+1, len(this.data))
+			}
+			this.viOperate(operator, start, end)
+		}
+		return nil
+	}
+
+	if !key.isCharacter() {
+		if action, bound := this.keyMap[key]; bound {
+			return this.perform(action)
+		}
+		return nil
+	}
+
+	switch key.Code {
+	case 'h', 'l', 'w', 'b', 'e', '0', '$':
+		this.cursorPosition, _, _ = this.viMotion(key.Code, 0)
+	case 'i':
+		this.viInsertMode()
+	case 'a':
+		this.cursorPosition = min(this.cursorPosition+1, len(this.data))
+		this.viInsertMode()
+	case 'I':
+		this.cursorPosition = 0
+		this.viInsertMode()
+	case 'A':
+		this.cursorPosition = len(this.data)
+		this.viInsertMode()
+	case 'x':
+		if this.cursorPosition < len(this.data) {
+			this.viOperate('d', this.cursorPosition, graphemeRight(this.data, this.cursorPosition))
+		}
+	case 'D':
+		this.viOperate('d', this.cursorPosition, len(this.data))
+	case 'C':
+		this.viOperate('c', this.cursorPosition, len(this.data))
+	case 'r':
+		this.vi.replacing = true
+	case 'd', 'c', 'y':
+		this.vi.operator = key.Code
+	case 'p':
+		this.viPut(min(this.cursorPosition+1, len(this.data)))
+	case 'P':
+		this.viPut(this.cursorPosition)
+	case 'k':
+		return this.previousHistory()
+	case 'j':
+		return this.nextHistory()
+	}
+	return nil
+}
+
+// viMotion returns the target of the motion from the cursor and whether an
+// operator includes the character at the target. The c operator treats w as
+// e, as vi does.
+func (this *CLE) viMotion(motion, operator rune) (target int, inclusive, ok bool) {
+	position := this.cursorPosition
+	switch motion {
+	case 'h':
+		return graphemeLeft(this.data, position), false, true
+	case 'l':
+		return graphemeRight(this.data, position), false, true
+	case 'w':
+		if operator == 'c' && position < len(this.data) && this.data[position] != ' ' {
+			return this.viWordEnd(position), true, true
+		}
+		for position < len(this.data) && this.data[position] != ' ' {
+			position++
+		}
+		for position < len(this.data) && this.data[position] == ' ' {
+			position++
+		}
+		return position, false, true
+	case 'b':
+		return wordLeft(this.data, position), false, true
+	case 'e':
+		return this.viWordEnd(position), true, true
+	case '0':
+		return 0, false, true
+	case '$':
+		return len(this.data), false, true
+	}
+	return position, false, false
+}
+
+// viWordEnd returns the position of the last character of the word ending
+// after position.
+func (this *CLE) viWordEnd(position int) int {
+	return max(wordRight(this.data, min(position+1, len(this.data)))-1, 0)
+}
+
+// viOperate deletes (d), changes (c) or yanks (y) the input from start to end.
+func (this *CLE) viOperate(operator rune, start, end int) {
+	this.vi.register = append([]rune(nil), this.data[start:end]...)
+	this.cursorPosition = start
+	if operator == 'y' {
+		return
+	}
+
+	this.data = append(this.data[:start], this.data[end:]...)
+	this.vi.changed = true
+	if operator == 'c' {
+		this.viInsertMode()
+	}
+}
+
+func (this *CLE) viPut(position int) {
+	if len(this.vi.register) == 0 {
+		return
+	}
+	for i, r := range this.vi.register {
+		this.data = insert(this.data, position+i, r)
+	}
+	this.cursorPosition = position + len(this.vi.register) - 1
+	this.vi.changed = true
+}
+
+func (this *CLE) viInsertMode() {
+	this.vi.normal = false
+	this.vi.changed = true // whatever is typed before Escape is part of the change
+}
+
+func (this *CLE) viNormalMode() {
+	this.vi.normal = true
+	this.cursorPosition = this.normalCursor(graphemeLeft(this.data, this.cursorPosition))
+}
+
+// finishViCommand records the command just completed, when it changed the
+// input, so it can be repeated with . (The edit history records it as one
+// edit, to be undone with u.)
+func (this *CLE) finishViCommand() {
+	if this.vi.command != nil && this.vi.changed {
+		this.vi.lastChange = this.vi.command
+	}
+	this.vi.command, this.vi.changed = nil, false
+}
+
+// viUndo undoes (u) or redoes (CTL-R) an edit, leaving the cursor on a character.
+func (this *CLE) viUndo(restore func() error) {
+	this.handleError(restore())
+	this.cursorPosition = this.normalCursor(this.cursorPosition)
+	this.repaint()
+}
+
+func (this *CLE) viRepeat() error {
+	for _, key := range append([]Key(nil), this.vi.lastChange...) {
+		if err := this.dispatchKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalCursor keeps the cursor on a character, as normal mode has no
+// position after the end of the input.
+func (this *CLE) normalCursor(position int) int {
+	if position >= len(this.data) {
+		return graphemeLeft(this.data, len(this.data))
+	}
+	return max(position, 0)
+}
+
+func (this *CLE) viModeIndicator() string {
+	if this.vi.normal {
+		return VI_NORMAL_MODE_INDICATOR
+	}
+	return VI_INSERT_MODE_INDICATOR
+}
+
+////////////////////////////////////////////
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
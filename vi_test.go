@@ -0,0 +1,189 @@
+package cle
+
+import (
+	"testing"
+
+	"github.com/smarty/assertions/should"
+	"github.com/smarty/gunit"
+)
+
+func TestViFixture(t *testing.T) {
+	gunit.Run(new(ViFixture), t)
+}
+
+type ViFixture struct {
+	*gunit.Fixture
+	*lineEditing
+}
+
+func (this *ViFixture) Setup() {
+	this.lineEditing = newLineEditing(this.Fixture, NewCLE(TestMode(true), ViMode(true)))
+	this.cle.resetVi()
+}
+
+func (this *ViFixture) TestInsertModeThenNormalMode() {
+	this.So(this.cle.viModeIndicator(), should.Equal, VI_INSERT_MODE_INDICATOR)
+	this.typeText("hello world")
+	this.assertLine("hello world", 11)
+
+	this.typeText("\x1b")
+	this.So(this.cle.vi.normal, should.BeTrue)
+	this.So(this.cle.viModeIndicator(), should.Equal, VI_NORMAL_MODE_INDICATOR)
+	this.assertLine("hello world", 10)
+}
+
+func (this *ViFixture) TestMotions() {
+	this.typeText("one two three\x1b")
+
+	this.typeText("0")
+	this.assertLine("one two three", 0)
+	this.typeText("w")
+	this.assertLine("one two three", 4)
+	this.typeText("e")
+	this.assertLine("one two three", 6)
+	this.typeText("e")
+	this.assertLine("one two three", 12)
+	this.typeText("b")
+	this.assertLine("one two three", 8)
+	this.typeText("h")
+	this.assertLine("one two three", 7)
+	this.typeText("l")
+	this.assertLine("one two three", 8)
+	this.typeText("$")
+	this.assertLine("one two three", 12)
+	this.typeText("l")
+	this.assertLine("one two three", 12)
+}
+
+func (this *ViFixture) TestOperators() {
+	this.typeText("one two three\x1b0")
+
+	this.typeText("dw")
+	this.assertLine("two three", 0)
+	this.So(string(this.cle.vi.register), should.Equal, "one ")
+
+	this.typeText("de")
+	this.assertLine(" three", 0)
+
+	this.typeText("wyep")
+	this.assertLine(" tthreehree", 6)
+
+	this.typeText("0cwnew\x1b") // on a blank, cw changes the blanks like dw
+	this.assertLine("newtthreehree", 2)
+
+	this.typeText("d$")
+	this.assertLine("ne", 1)
+
+	this.typeText("dd")
+	this.assertLine("", 0)
+}
+
+func (this *ViFixture) TestChangeWordKeepsFollowingSpace() {
+	this.typeText("one two\x1b0cwsix\x1b")
+	this.assertLine("six two", 2)
+}
+
+func (this *ViFixture) TestDeleteAndReplaceCharacters() {
+	this.typeText("abcd\x1b0")
+
+	this.typeText("x")
+	this.assertLine("bcd", 0)
+
+	this.typeText("rz")
+	this.assertLine("zcd", 0)
+
+	this.typeText("$x")
+	this.assertLine("zc", 1)
+}
+
+func (this *ViFixture) TestInsertCommands() {
+	this.typeText("bc\x1b0")
+
+	this.typeText("Ia\x1b")
+	this.assertLine("abc", 0)
+	this.typeText("Ad\x1b")
+	this.assertLine("abcd", 3)
+	this.typeText("0ax\x1b")
+	this.assertLine("axbcd", 1)
+}
+
+func (this *ViFixture) TestUndo() {
+	this.typeText("one two three\x1b0")
+	this.typeText("dw")
+	this.typeText("x")
+	this.assertLine("wo three", 0)
+
+	this.typeText("u")
+	this.assertLine("two three", 0)
+	this.typeText("u")
+	this.assertLine("one two three", 0)
+	this.typeText("u") // the text typed when the line began
+	this.assertLine("", 0)
+	this.typeText("u")
+	this.assertLine("", 0)
+}
+
+func (this *ViFixture) TestRedo() {
+	this.typeText("one two three\x1b0")
+	this.typeText("dwx")
+	this.typeText("uu")
+	this.assertLine("one two three", 0)
+
+	this.cle.handleKey(Ctrl('r'))
+	this.assertLine("two three", 0)
+	this.cle.handleKey(Ctrl('r'))
+	this.assertLine("wo three", 0)
+	this.cle.handleKey(Ctrl('r'))
+	this.assertLine("wo three", 0)
+}
+
+func (this *ViFixture) TestUndoInsertCommandAsOneEdit() {
+	this.typeText("one\x1b")
+	this.typeText("A two three\x1b")
+	this.assertLine("one two three", 12)
+
+	this.typeText("u")
+	this.assertLine("one", 2)
+}
+
+func (this *ViFixture) TestRepeatLastChange() {
+	this.typeText("one two three four\x1b0")
+
+	this.typeText("dw")
+	this.typeText(".")
+	this.assertLine("three four", 0)
+
+	this.typeText("cwsix\x1bw")
+	this.typeText(".")
+	this.assertLine("six six", 6)
+
+	this.typeText("u")
+	this.assertLine("six four", 4)
+}
+
+func (this *ViFixture) TestYankDoesNotChange() {
+	this.typeText("one two\x1b0x")
+	this.typeText("yw")
+	this.assertLine("ne two", 0)
+	this.So(string(this.cle.vi.register), should.Equal, "ne ")
+
+	this.typeText(".") // repeats x, not yw
+	this.assertLine("e two", 0)
+}
+
+func (this *ViFixture) TestNormalModeFallsBackToKeyMap() {
+	this.typeText("command\x1b")
+	this.So(this.cle.handleKey(Key{Code: ENTER_KEY}), should.Equal, errLineAccepted)
+}
+
+func (this *ViFixture) TestHistoryNavigation() {
+	this.cle.history.commands = [][]byte{[]byte("first"), []byte("second")}
+	this.cle.history.currentPosition = 2
+
+	this.typeText("\x1bk")
+	this.assertLine("second", 5)
+	this.typeText("k")
+	this.assertLine("first", 4)
+	this.typeText("j")
+	this.assertLine("second", 5)
+}